@@ -65,3 +65,41 @@ func TestGetSet_SetShouldReturnError(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestGetSet_WithFake(t *testing.T) {
+	fake := memcachemock.NewFake()
+	item := &memcache.Item{
+		Key:   "foo",
+		Value: []byte("my value"),
+	}
+	it, err := SetAndGet(fake, item)
+	require.NoError(t, err)
+	require.Equal(t, item.Value, it.Value)
+}
+
+func TestGetSet_WithNewT(t *testing.T) {
+	mock := memcachemock.NewT(t)
+	item := &memcache.Item{
+		Key:   "foo",
+		Value: []byte("my value"),
+	}
+	mock.ExpectSet().
+		WithItem(item)
+	mock.ExpectGet().
+		WithKey("foo").
+		WillReturnItem(item)
+	it, err := SetAndGet(mock, item)
+	require.NoError(t, err)
+	require.Equal(t, item, it)
+}
+
+func TestGetSet_StrictModeRejectsInvalidKey(t *testing.T) {
+	mock := memcachemock.NewT(t, memcachemock.Strict())
+	item := &memcache.Item{
+		Key:   "invalid key",
+		Value: []byte("my value"),
+	}
+	it, err := SetAndGet(mock, item)
+	require.ErrorIs(t, err, memcache.ErrMalformedKey)
+	require.Nil(t, it)
+}
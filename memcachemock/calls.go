@@ -0,0 +1,275 @@
+package memcachemock
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// TestingT is the subset of testing.TB used to report assertion failures.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Call is a call made to a mocked method, whether it was expected or not.
+type Call struct {
+	Method     string        // name of the method, e.g. "Get"
+	Args       []interface{} // arguments of the call, items and keys being copied
+	Returns    []interface{} // returned values, without the error
+	Err        error         // returned error
+	Panicked   bool          // whether the call panicked, set up with WillPanic
+	PanicValue interface{}   // value the call panicked with
+	Time       time.Time     // when the call was made
+	Index      int           // position of the call among all the calls made to the mock
+	Caller     string        // file and line the call was made from
+	Server     string        // address of the server the key was routed to, if the mock has servers
+}
+
+// String returns string representation
+func (c Call) String() string {
+	msg := fmt.Sprintf("#%d %s", c.Index, c.signature())
+	if c.Panicked {
+		msg += fmt.Sprintf(" => panic: %v", c.PanicValue)
+	} else if c.Err != nil {
+		msg += fmt.Sprintf(" => error: %v", c.Err)
+	}
+	return msg
+}
+
+// CallArgs are the arguments of a call, given to the functions set with Do.
+// Only the arguments taken by the method are set.
+type CallArgs struct {
+	Method  string         // name of the method, e.g. "Get"
+	Key     string         // key of Get, Delete, Touch, Increment and Decrement
+	Keys    []string       // keys of GetMulti
+	Item    *memcache.Item // item of Add, Append, CompareAndSwap, Prepend, Replace and Set
+	Delta   uint64         // delta of Increment and Decrement
+	Seconds int32          // seconds of Touch
+}
+
+// args returns the arguments of the call
+func (c *Call) args() CallArgs {
+	args := CallArgs{Method: c.Method}
+	for _, arg := range c.Args {
+		switch a := arg.(type) {
+		case string:
+			args.Key = a
+		case []string:
+			args.Keys = a
+		case *memcache.Item:
+			args.Item = a
+		case uint64:
+			args.Delta = a
+		case int32:
+			args.Seconds = a
+		}
+	}
+	return args
+}
+
+// signature returns the method called and its arguments, e.g. Get("some-key")
+func (c Call) signature() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = formatArg(arg)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method, strings.Join(args, ", "))
+}
+
+func formatArg(arg interface{}) string {
+	switch a := arg.(type) {
+	case *memcache.Item:
+		if a == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("&{Key:%s Value:%s Flags:%d Expiration:%d CasID:%d}", a.Key, string(a.Value), a.Flags, a.Expiration, a.CasID)
+	case string:
+		return fmt.Sprintf("%q", a)
+	}
+	return fmt.Sprintf("%v", arg)
+}
+
+// startCall records a call to method with the given arguments, made by the caller of the mocked method.
+// The returned call must be completed with finishCall.
+func (c *Mock) startCall(method string, args ...interface{}) *Call {
+	for i, arg := range args {
+		switch a := arg.(type) {
+		case *memcache.Item:
+			if a != nil {
+				args[i] = copyItem(a)
+			}
+		case []string:
+			if a != nil {
+				args[i] = append([]string{}, a...)
+			}
+		}
+	}
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	call := &Call{
+		Method: method,
+		Args:   args,
+		Time:   time.Now(),
+		Index:  len(c.calls),
+		Caller: caller,
+	}
+	c.calls = append(c.calls, call)
+	return call
+}
+
+// finishCall records the values returned by a call.
+func (c *Mock) finishCall(call *Call, err error, returns ...interface{}) {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	call.Returns = returns
+	call.Err = err
+}
+
+// panicCall records that the call panicked with v.
+func (c *Mock) panicCall(call *Call, v interface{}) {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	call.Panicked = true
+	call.PanicValue = v
+}
+
+// Calls returns every call made to the mock, in order.
+func (c *Mock) Calls() []Call {
+	return c.CallsTo("")
+}
+
+// CallsTo returns the calls made to the given method, e.g. "Get", in order.
+// An empty method returns every call.
+func (c *Mock) CallsTo(method string) []Call {
+	method = strings.TrimSuffix(method, "()")
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	var calls []Call
+	for _, call := range c.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, *call)
+		}
+	}
+	return calls
+}
+
+// AssertCalled asserts that method was called with the given arguments.
+// Each argument is either a literal value or a Matcher. Without arguments, any call matches.
+func (c *Mock) AssertCalled(t TestingT, method string, args ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	calls := c.CallsTo(method)
+	for _, call := range calls {
+		if callMatches(call, args) {
+			return true
+		}
+	}
+	t.Errorf("expected a call to %s%s, but got:\n%s", method, describeArgs(args), describeCalls(calls))
+	return false
+}
+
+// AssertNotCalled asserts that method was never called with the given arguments.
+// Each argument is either a literal value or a Matcher. Without arguments, no call to method is allowed.
+func (c *Mock) AssertNotCalled(t TestingT, method string, args ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	for _, call := range c.CallsTo(method) {
+		if callMatches(call, args) {
+			t.Errorf("expected no call to %s%s, but got: %s", method, describeArgs(args), call)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertNumberOfCalls asserts that method was called exactly n times.
+func (c *Mock) AssertNumberOfCalls(t TestingT, method string, n int) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	calls := c.CallsTo(method)
+	if len(calls) != n {
+		t.Errorf("expected %d calls to %s, but got %d:\n%s", n, method, len(calls), describeCalls(calls))
+		return false
+	}
+	return true
+}
+
+func callMatches(call Call, args []interface{}) bool {
+	if len(args) == 0 {
+		return true
+	}
+	if len(args) != len(call.Args) {
+		return false
+	}
+	for i, arg := range args {
+		if !argMatches(arg, call.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// argMatches tells whether the actual argument of a call matches the expected value or Matcher,
+// converting literal values the same way the With* builders do.
+func argMatches(expected, actual interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	if m, isMatcher := expected.(Matcher); isMatcher {
+		return m.Match(actual)
+	}
+	switch a := actual.(type) {
+	case string:
+		return toKeyMatcher(expected).Match(a)
+	case []string:
+		return toKeysMatcher(expected).Match(a)
+	case uint64:
+		return toDeltaMatcher(expected).Match(a)
+	case int32:
+		return toSecondsMatcher(expected).Match(a)
+	case *memcache.Item:
+		var e itemBasedExpectation
+		e.setItem(expected)
+		return e.itemMatches(a) == nil
+	}
+	return Eq(expected).Match(actual)
+}
+
+func describeArgs(args []interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	desc := make([]string, len(args))
+	for i, arg := range args {
+		if m, ok := arg.(Matcher); ok {
+			desc[i] = m.String()
+			continue
+		}
+		desc[i] = formatArg(arg)
+	}
+	return "(" + strings.Join(desc, ", ") + ")"
+}
+
+func describeCalls(calls []Call) string {
+	if len(calls) == 0 {
+		return "\t- no calls"
+	}
+	lines := make([]string, len(calls))
+	for i, call := range calls {
+		lines[i] = "\t- " + call.String()
+	}
+	return strings.Join(lines, "\n")
+}
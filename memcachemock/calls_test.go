@@ -0,0 +1,126 @@
+package memcachemock
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingT records the failures reported by assertions
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCalls(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	item := &memcache.Item{Key: "some-key", Value: []byte("some value")}
+	mock.ExpectSet().WithItem(item)
+	mock.ExpectGet().WithKey("some-key").WillReturnItem(item)
+	mock.ExpectIncrement().WithKeyAndDelta("counter", 2).WillReturnValue(4)
+
+	before := time.Now()
+	a.NoError(mock.Set(item))
+	item.Value = []byte("changed after the call")
+	_, err := mock.Get("some-key")
+	a.NoError(err)
+	_, err = mock.Increment("counter", 2)
+	a.NoError(err)
+	a.Error(mock.Ping())
+
+	calls := mock.Calls()
+	a.Len(calls, 4)
+	for i, call := range calls {
+		a.Equal(i, call.Index)
+		a.False(call.Time.Before(before))
+	}
+	a.Equal("Set", calls[0].Method)
+	a.Equal([]byte("some value"), calls[0].Args[0].(*memcache.Item).Value)
+	a.Equal("Get", calls[1].Method)
+	a.Equal([]interface{}{"some-key"}, calls[1].Args)
+	a.Equal([]interface{}{item}, calls[1].Returns)
+	a.Equal([]interface{}{"counter", uint64(2)}, calls[2].Args)
+	a.Equal([]interface{}{uint64(4)}, calls[2].Returns)
+	a.Equal("Ping", calls[3].Method)
+	a.Error(calls[3].Err)
+
+	a.Len(mock.CallsTo("Get"), 1)
+	a.Len(mock.CallsTo("Get()"), 1)
+	a.Empty(mock.CallsTo("Delete"))
+}
+
+func TestAssertCalled(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectSet().WithItem(MatchItem())
+	mock.ExpectTouch().WithKeyAndSeconds(AnyKey(), AnySeconds())
+	mock.ExpectDecrement().WithKeyAndDelta(AnyKey(), AnyDelta())
+	mock.ExpectGetMulti().WithKeys(AnyKeys())
+	a.NoError(mock.Set(&memcache.Item{Key: "user:1", Value: []byte("some value")}))
+	a.NoError(mock.Touch("user:1", 60))
+	_, err := mock.Decrement("counter", 3)
+	a.NoError(err)
+	_, err = mock.GetMulti([]string{"a", "b"})
+	a.NoError(err)
+
+	rt := &recordingT{}
+	a.True(mock.AssertCalled(rt, "Set"))
+	a.True(mock.AssertCalled(rt, "Set", MatchItem().Key(KeyPrefix("user:"))))
+	a.True(mock.AssertCalled(rt, "Set", &memcache.Item{Key: "user:1", Value: []byte("some value")}))
+	a.True(mock.AssertCalled(rt, "Touch", "user:1", 60))
+	a.True(mock.AssertCalled(rt, "Decrement", "counter", DeltaBetween(1, 5)))
+	a.True(mock.AssertCalled(rt, "GetMulti", []string{"a", "b"}))
+	a.Empty(rt.errors)
+
+	a.False(mock.AssertCalled(rt, "Touch", "user:1", 30))
+	a.False(mock.AssertCalled(rt, "Touch", "user:1"))
+	a.False(mock.AssertCalled(rt, "Touch", "user:1", "not seconds"))
+	a.False(mock.AssertCalled(rt, "Get"))
+	a.Len(rt.errors, 4)
+	a.Equal("expected a call to Touch(\"user:1\", 30), but got:\n\t- #1 Touch(\"user:1\", 60)", rt.errors[0])
+	a.Equal("expected a call to Get, but got:\n\t- no calls", rt.errors[3])
+}
+
+func TestAssertNotCalled(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectDelete().WithKey("some-key").WillReturnError(memcache.ErrCacheMiss)
+	a.ErrorIs(mock.Delete("some-key"), memcache.ErrCacheMiss)
+
+	rt := &recordingT{}
+	a.True(mock.AssertNotCalled(rt, "Get"))
+	a.True(mock.AssertNotCalled(rt, "Delete", "another-key"))
+	a.Empty(rt.errors)
+	a.False(mock.AssertNotCalled(rt, "Delete", KeyPrefix("some")))
+	a.Equal([]string{`expected no call to Delete(with prefix "some"), but got: #0 Delete("some-key") => error: memcache: cache miss`}, rt.errors)
+}
+
+func TestAssertNumberOfCalls(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectPing().Times(2)
+	a.NoError(mock.Ping())
+	a.NoError(mock.Ping())
+
+	rt := &recordingT{}
+	a.True(mock.AssertNumberOfCalls(rt, "Ping", 2))
+	a.True(mock.AssertNumberOfCalls(rt, "Close", 0))
+	a.False(mock.AssertNumberOfCalls(rt, "Ping", 3))
+	a.Equal([]string{"expected 3 calls to Ping, but got 2:\n\t- #0 Ping()\n\t- #1 Ping()"}, rt.errors)
+}
+
+func TestArgMatches(t *testing.T) {
+	a := assert.New(t)
+	a.True(argMatches(nil, (*memcache.Item)(nil)))
+	a.False(argMatches(10, "some-key"))
+	a.True(argMatches(errors.New("x"), errors.New("x")))
+	a.Equal("nil", formatArg((*memcache.Item)(nil)))
+}
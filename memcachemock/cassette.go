@@ -0,0 +1,266 @@
+package memcachemock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// cassetteEntry is a call recorded by a Recorder, one per line of a cassette
+type cassetteEntry struct {
+	Method  string                    `json:"method"`
+	Key     *string                   `json:"key,omitempty"`
+	Keys    []string                  `json:"keys"` // keys and results are never omitted, to replay empty ones as such
+	Item    *memcache.Item            `json:"item,omitempty"`
+	Delta   *uint64                   `json:"delta,omitempty"`
+	Seconds *int32                    `json:"seconds,omitempty"`
+	Result  *memcache.Item            `json:"result,omitempty"`
+	Results map[string]*memcache.Item `json:"results"`
+	Value   *uint64                   `json:"value,omitempty"`
+	Error   string                    `json:"error,omitempty"` // name of a memcache.Err* error, or message of another error
+}
+
+// Recorder is a Client recording every call made to another Client, along with its result, to a cassette.
+// A cassette holds one JSON document per call, and is turned back into expectations by Replay.
+// A Recorder is safe for concurrent use if the recorded client is.
+type Recorder struct {
+	client Client
+	mu     sync.Mutex
+	enc    *json.Encoder
+	err    error // first error writing the cassette
+}
+
+var _ Client = (*Recorder)(nil)
+
+// NewRecorder returns a Client calling client, and writing the calls to the cassette w.
+// client may be a *memcache.Client connected to a real memcached, or to the embedded server of package server.
+func NewRecorder(client Client, w io.Writer) *Recorder {
+	return &Recorder{client: client, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered while writing the cassette, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// record writes the entry to the cassette, with the error returned by the call
+func (r *Recorder) record(entry cassetteEntry, err error) {
+	entry.Error = errorName(err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(entry)
+}
+
+func (r *Recorder) Add(item *memcache.Item) error {
+	err := r.client.Add(item)
+	r.record(cassetteEntry{Method: "Add", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Append(item *memcache.Item) error {
+	err := r.client.Append(item)
+	r.record(cassetteEntry{Method: "Append", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Close() error {
+	err := r.client.Close()
+	r.record(cassetteEntry{Method: "Close"}, err)
+	return err
+}
+
+func (r *Recorder) CompareAndSwap(item *memcache.Item) error {
+	err := r.client.CompareAndSwap(item)
+	r.record(cassetteEntry{Method: "CompareAndSwap", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Decrement(key string, delta uint64) (newValue uint64, err error) {
+	newValue, err = r.client.Decrement(key, delta)
+	r.record(cassetteEntry{Method: "Decrement", Key: &key, Delta: &delta, Value: &newValue}, err)
+	return newValue, err
+}
+
+func (r *Recorder) Delete(key string) error {
+	err := r.client.Delete(key)
+	r.record(cassetteEntry{Method: "Delete", Key: &key}, err)
+	return err
+}
+
+func (r *Recorder) DeleteAll() error {
+	err := r.client.DeleteAll()
+	r.record(cassetteEntry{Method: "DeleteAll"}, err)
+	return err
+}
+
+func (r *Recorder) FlushAll() error {
+	err := r.client.FlushAll()
+	r.record(cassetteEntry{Method: "FlushAll"}, err)
+	return err
+}
+
+func (r *Recorder) Get(key string) (item *memcache.Item, err error) {
+	item, err = r.client.Get(key)
+	r.record(cassetteEntry{Method: "Get", Key: &key, Result: item}, err)
+	return item, err
+}
+
+func (r *Recorder) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	items, err := r.client.GetMulti(keys)
+	r.record(cassetteEntry{Method: "GetMulti", Keys: keys, Results: items}, err)
+	return items, err
+}
+
+func (r *Recorder) Increment(key string, delta uint64) (newValue uint64, err error) {
+	newValue, err = r.client.Increment(key, delta)
+	r.record(cassetteEntry{Method: "Increment", Key: &key, Delta: &delta, Value: &newValue}, err)
+	return newValue, err
+}
+
+func (r *Recorder) Ping() error {
+	err := r.client.Ping()
+	r.record(cassetteEntry{Method: "Ping"}, err)
+	return err
+}
+
+func (r *Recorder) Prepend(item *memcache.Item) error {
+	err := r.client.Prepend(item)
+	r.record(cassetteEntry{Method: "Prepend", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Replace(item *memcache.Item) error {
+	err := r.client.Replace(item)
+	r.record(cassetteEntry{Method: "Replace", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Set(item *memcache.Item) error {
+	err := r.client.Set(item)
+	r.record(cassetteEntry{Method: "Set", Item: item}, err)
+	return err
+}
+
+func (r *Recorder) Touch(key string, seconds int32) (err error) {
+	err = r.client.Touch(key, seconds)
+	r.record(cassetteEntry{Method: "Touch", Key: &key, Seconds: &seconds}, err)
+	return err
+}
+
+// Replay returns a mock expecting the calls recorded in the cassette r, in the same order,
+// and returning the same results. Entries are read as a stream of JSON values, whatever their size.
+func Replay(r io.Reader) (*Mock, error) {
+	mock := New()
+	dec := json.NewDecoder(r)
+	for n := 1; dec.More(); n++ {
+		var entry cassetteEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("memcachemock: cassette entry %d: %w", n, err)
+		}
+		if err := entry.expect(mock); err != nil {
+			return nil, fmt.Errorf("memcachemock: cassette entry %d: %w", n, err)
+		}
+	}
+	return mock, nil
+}
+
+// ReplayFile returns a mock expecting the calls recorded in the cassette file at path.
+func ReplayFile(path string) (*Mock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Replay(f)
+}
+
+// expect adds the expectation of the recorded call to the mock
+func (entry cassetteEntry) expect(c *Mock) error {
+	var key string
+	if entry.Key != nil {
+		key = *entry.Key
+	}
+	var delta, value uint64
+	if entry.Delta != nil {
+		delta = *entry.Delta
+	}
+	if entry.Value != nil {
+		value = *entry.Value
+	}
+	var modifier CallModifier
+	switch entry.Method {
+	case "Add":
+		modifier = c.ExpectAdd().WithItem(entry.Item)
+	case "Append":
+		modifier = c.ExpectAppend().WithItem(entry.Item)
+	case "Close":
+		modifier = c.ExpectClose()
+	case "CompareAndSwap":
+		modifier = c.ExpectCompareAndSwap().WithItem(entry.Item)
+	case "Decrement":
+		modifier = c.ExpectDecrement().WithKeyAndDelta(key, delta).WillReturnValue(value)
+	case "Delete":
+		modifier = c.ExpectDelete().WithKey(key)
+	case "DeleteAll":
+		modifier = c.ExpectDeleteAll()
+	case "FlushAll":
+		modifier = c.ExpectFlushAll()
+	case "Get":
+		modifier = c.ExpectGet().WithKey(key).WillReturnItem(entry.Result)
+	case "GetMulti":
+		modifier = c.ExpectGetMulti().WithKeys(entry.Keys).WillReturnItems(entry.Results)
+	case "Increment":
+		modifier = c.ExpectIncrement().WithKeyAndDelta(key, delta).WillReturnValue(value)
+	case "Ping":
+		modifier = c.ExpectPing()
+	case "Prepend":
+		modifier = c.ExpectPrepend().WithItem(entry.Item)
+	case "Replace":
+		modifier = c.ExpectReplace().WithItem(entry.Item)
+	case "Set":
+		modifier = c.ExpectSet().WithItem(entry.Item)
+	case "Touch":
+		var seconds int32
+		if entry.Seconds != nil {
+			seconds = *entry.Seconds
+		}
+		modifier = c.ExpectTouch().WithKeyAndSeconds(key, seconds)
+	default:
+		return fmt.Errorf("unknown method %q", entry.Method)
+	}
+	if entry.Error != "" {
+		modifier.WillReturnError(namedError(entry.Error))
+	}
+	return nil
+}
+
+// errorName returns the name of a memcache.Err* error, or the message of any other error
+func errorName(err error) string {
+	if err == nil {
+		return ""
+	}
+	for name, e := range fixtureErrors {
+		if errors.Is(err, e) {
+			return name
+		}
+	}
+	return err.Error()
+}
+
+// namedError returns the error named by errorName
+func namedError(name string) error {
+	if err, ok := fixtureErrors[name]; ok {
+		return err
+	}
+	return errors.New(name)
+}
@@ -0,0 +1,152 @@
+package memcachemock
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheScenario makes a call to every method of client
+func cacheScenario(client Client) []interface{} {
+	var results []interface{}
+	get := func(key string) {
+		item, err := client.Get(key)
+		results = append(results, item, err)
+	}
+	get("user:1")
+	results = append(results, client.Set(&memcache.Item{Key: "user:1", Value: []byte{0, 1, 0xff}, Flags: 2, Expiration: 60}))
+	get("user:1")
+	results = append(results, client.Add(&memcache.Item{Key: "user:1"}))
+	results = append(results, client.Append(&memcache.Item{Key: "user:1", Value: []byte("a")}))
+	results = append(results, client.Prepend(&memcache.Item{Key: "user:1", Value: []byte("p")}))
+	results = append(results, client.Replace(&memcache.Item{Key: "user:2"}))
+	item, _ := client.Get("user:1")
+	item.Value = []byte("swapped")
+	results = append(results, client.CompareAndSwap(item))
+	items, err := client.GetMulti([]string{"user:1", "user:2"})
+	results = append(results, items, err)
+	results = append(results, client.Set(&memcache.Item{Key: "counter", Value: []byte("10")}))
+	value, err := client.Increment("counter", 5)
+	results = append(results, value, err)
+	value, err = client.Decrement("counter", 20)
+	results = append(results, value, err)
+	results = append(results, client.Touch("user:1", 10), client.Delete("user:2"))
+	results = append(results, client.Ping(), client.FlushAll(), client.DeleteAll(), client.Close())
+	return results
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	a := assert.New(t)
+	var cassette bytes.Buffer
+	recorder := NewRecorder(NewFake(), &cassette)
+	recorded := cacheScenario(recorder)
+	require.NoError(t, recorder.Err())
+	a.Equal(19, strings.Count(cassette.String(), "\n"), "one line per call")
+	a.Contains(cassette.String(), `"error":"ErrCacheMiss"`)
+
+	mock, err := Replay(&cassette)
+	require.NoError(t, err)
+	a.Equal(recorded, cacheScenario(mock))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestReplay_OtherErrors(t *testing.T) {
+	a := assert.New(t)
+	var cassette bytes.Buffer
+	mock := New()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	recorder := NewRecorder(mock, &cassette)
+	a.EqualError(recorder.Ping(), "connection refused")
+
+	replay, err := Replay(&cassette)
+	require.NoError(t, err)
+	a.EqualError(replay.Ping(), "connection refused")
+}
+
+func TestReplay_Ordered(t *testing.T) {
+	a := assert.New(t)
+	replay, err := Replay(strings.NewReader(`{"method":"Delete","key":"a"}` + "\n\n" + `{"method":"Delete","key":"b","error":"ErrCacheMiss"}` + "\n"))
+	require.NoError(t, err)
+	a.Error(replay.Delete("b"))
+	a.NoError(replay.Delete("a"))
+	a.ErrorIs(replay.Delete("b"), memcache.ErrCacheMiss)
+	a.NoError(replay.ExpectationsWereMet())
+}
+
+func TestReplay_Invalid(t *testing.T) {
+	a := assert.New(t)
+	_, err := Replay(strings.NewReader(`{"method":"Ping"}` + "\n" + `{"method":`))
+	a.ErrorContains(err, "cassette entry 2")
+	_, err = Replay(strings.NewReader(`{"method":"Stats"}`))
+	a.ErrorContains(err, `cassette entry 1: unknown method "Stats"`)
+}
+
+func TestRecordAndReplay_LargeItems(t *testing.T) {
+	a := assert.New(t)
+	fake := NewFake()
+	large := func(b byte) []byte { return bytes.Repeat([]byte{b}, 900*1024) }
+	a.NoError(fake.Set(&memcache.Item{Key: "a", Value: large('a')}))
+	a.NoError(fake.Set(&memcache.Item{Key: "b", Value: large('b')}))
+
+	var cassette bytes.Buffer
+	recorder := NewRecorder(fake, &cassette)
+	recorded, err := recorder.GetMulti([]string{"a", "b"})
+	a.NoError(err)
+	a.NoError(recorder.Err())
+	a.Greater(cassette.Len(), 2*maxItemSize)
+
+	replay, err := Replay(&cassette)
+	if a.NoError(err) {
+		items, err := replay.GetMulti([]string{"a", "b"})
+		a.NoError(err)
+		a.Equal(recorded, items)
+		a.NoError(replay.ExpectationsWereMet())
+	}
+}
+
+func TestRecordAndReplay_Empty(t *testing.T) {
+	a := assert.New(t)
+	var cassette bytes.Buffer
+	recorder := NewRecorder(NewFake(), &cassette)
+	none, err := recorder.GetMulti([]string{})
+	a.NoError(err)
+	missing, err := recorder.GetMulti([]string{"missing"})
+	a.NoError(err)
+	a.NoError(recorder.Ping())
+	require.NoError(t, recorder.Err())
+
+	replay, err := Replay(&cassette)
+	require.NoError(t, err)
+	items, err := replay.GetMulti([]string{})
+	a.NoError(err)
+	a.Equal(none, items)
+	items, err = replay.GetMulti([]string{"missing"})
+	a.NoError(err)
+	a.Equal(missing, items)
+	a.NotNil(items)
+	a.Empty(items)
+	a.NoError(replay.Ping())
+	a.NoError(replay.ExpectationsWereMet())
+}
+
+func TestReplayFile(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	recorder := NewRecorder(NewFake(), f)
+	a.ErrorIs(recorder.Delete("foo"), memcache.ErrCacheMiss)
+	require.NoError(t, f.Close())
+
+	mock, err := ReplayFile(path)
+	require.NoError(t, err)
+	a.ErrorIs(mock.Delete("foo"), memcache.ErrCacheMiss)
+	a.NoError(mock.ExpectationsWereMet())
+}
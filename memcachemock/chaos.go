@@ -0,0 +1,229 @@
+package memcachemock
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// ErrTimeout is a network timeout, as returned by a *memcache.Client when the server does not answer in time.
+// It satisfies net.Error, and errors.Is(ErrTimeout, os.ErrDeadlineExceeded).
+var ErrTimeout error = &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+
+// Chaos is a Client failing a fraction of the calls made to another Client, e.g. a Fake, with random errors.
+// The failing calls are not made to the wrapped client. The random source is seeded,
+// so that a sequence of calls fails the same way on every run with the same seed.
+// A Chaos is safe for concurrent use if the wrapped client is, though the failing calls then depend on scheduling.
+type Chaos struct {
+	client Client
+	mu     sync.Mutex
+	rand   *rand.Rand
+	rules  map[string]chaosRule // by method, "" applying to the methods without a rule
+	calls  int                  // number of calls made so far
+	faults []ChaosFault
+}
+
+// chaosRule fails a fraction of the calls with one of the errors
+type chaosRule struct {
+	rate float64
+	errs []error
+}
+
+// ChaosFault is a fault injected by a Chaos.
+type ChaosFault struct {
+	Call   int    // position of the call among all the calls made to the Chaos
+	Method string // name of the method, e.g. "Get"
+	Key    string // key of the call, or keys separated by commas for GetMulti, if any
+	Err    error  // injected error
+}
+
+// String returns string representation
+func (f ChaosFault) String() string {
+	if f.Key == "" {
+		return fmt.Sprintf("#%d %s() => error: %v", f.Call, f.Method, f.Err)
+	}
+	return fmt.Sprintf("#%d %s(%q) => error: %v", f.Call, f.Method, f.Key, f.Err)
+}
+
+var _ Client = (*Chaos)(nil)
+
+// NewChaos returns a Client calling client, failing no call until Fail or FailAll is called.
+func NewChaos(client Client, seed int64) *Chaos {
+	return &Chaos{client: client, rand: rand.New(rand.NewSource(seed)), rules: map[string]chaosRule{}}
+}
+
+// Fail makes a fraction rate, from 0 to 1, of the calls to method fail with one of errs, picked at random.
+// Without errors, memcache.ErrServerError is used. It panics if rate is out of bounds.
+func (c *Chaos) Fail(method string, rate float64, errs ...error) *Chaos {
+	if rate < 0 || rate > 1 {
+		panic(fmt.Sprintf("memcachemock: chaos rate must be between 0 and 1, got %v", rate))
+	}
+	if len(errs) == 0 {
+		errs = []error{memcache.ErrServerError}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules[strings.TrimSuffix(method, "()")] = chaosRule{rate: rate, errs: errs}
+	return c
+}
+
+// FailAll makes a fraction rate of the calls to the methods without a rule set by Fail fail with one of errs.
+func (c *Chaos) FailAll(rate float64, errs ...error) *Chaos {
+	return c.Fail("", rate, errs...)
+}
+
+// Faults returns the faults injected so far, in order.
+func (c *Chaos) Faults() []ChaosFault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ChaosFault(nil), c.faults...)
+}
+
+// Report describes the faults injected so far, one per line.
+func (c *Chaos) Report() string {
+	faults := c.Faults()
+	if len(faults) == 0 {
+		return "no faults injected"
+	}
+	lines := make([]string, len(faults))
+	for i, f := range faults {
+		lines[i] = f.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inject returns the error to fail the call with, if any.
+// A random number is drawn for every call, so that the calls failing only depend on the seed and their order.
+func (c *Chaos) inject(method string, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	draw := c.rand.Float64()
+	rule, ok := c.rules[method]
+	if !ok {
+		rule = c.rules[""]
+	}
+	if draw >= rule.rate {
+		return nil
+	}
+	err := rule.errs[c.rand.Intn(len(rule.errs))]
+	c.faults = append(c.faults, ChaosFault{Call: c.calls - 1, Method: method, Key: strings.Join(keys, ","), Err: err})
+	return err
+}
+
+func (c *Chaos) Add(item *memcache.Item) error {
+	if err := c.inject("Add", item.Key); err != nil {
+		return err
+	}
+	return c.client.Add(item)
+}
+
+func (c *Chaos) Append(item *memcache.Item) error {
+	if err := c.inject("Append", item.Key); err != nil {
+		return err
+	}
+	return c.client.Append(item)
+}
+
+func (c *Chaos) Close() error {
+	if err := c.inject("Close"); err != nil {
+		return err
+	}
+	return c.client.Close()
+}
+
+func (c *Chaos) CompareAndSwap(item *memcache.Item) error {
+	if err := c.inject("CompareAndSwap", item.Key); err != nil {
+		return err
+	}
+	return c.client.CompareAndSwap(item)
+}
+
+func (c *Chaos) Decrement(key string, delta uint64) (newValue uint64, err error) {
+	if err := c.inject("Decrement", key); err != nil {
+		return 0, err
+	}
+	return c.client.Decrement(key, delta)
+}
+
+func (c *Chaos) Delete(key string) error {
+	if err := c.inject("Delete", key); err != nil {
+		return err
+	}
+	return c.client.Delete(key)
+}
+
+func (c *Chaos) DeleteAll() error {
+	if err := c.inject("DeleteAll"); err != nil {
+		return err
+	}
+	return c.client.DeleteAll()
+}
+
+func (c *Chaos) FlushAll() error {
+	if err := c.inject("FlushAll"); err != nil {
+		return err
+	}
+	return c.client.FlushAll()
+}
+
+func (c *Chaos) Get(key string) (item *memcache.Item, err error) {
+	if err := c.inject("Get", key); err != nil {
+		return nil, err
+	}
+	return c.client.Get(key)
+}
+
+func (c *Chaos) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	if err := c.inject("GetMulti", keys...); err != nil {
+		return nil, err
+	}
+	return c.client.GetMulti(keys)
+}
+
+func (c *Chaos) Increment(key string, delta uint64) (newValue uint64, err error) {
+	if err := c.inject("Increment", key); err != nil {
+		return 0, err
+	}
+	return c.client.Increment(key, delta)
+}
+
+func (c *Chaos) Ping() error {
+	if err := c.inject("Ping"); err != nil {
+		return err
+	}
+	return c.client.Ping()
+}
+
+func (c *Chaos) Prepend(item *memcache.Item) error {
+	if err := c.inject("Prepend", item.Key); err != nil {
+		return err
+	}
+	return c.client.Prepend(item)
+}
+
+func (c *Chaos) Replace(item *memcache.Item) error {
+	if err := c.inject("Replace", item.Key); err != nil {
+		return err
+	}
+	return c.client.Replace(item)
+}
+
+func (c *Chaos) Set(item *memcache.Item) error {
+	if err := c.inject("Set", item.Key); err != nil {
+		return err
+	}
+	return c.client.Set(item)
+}
+
+func (c *Chaos) Touch(key string, seconds int32) (err error) {
+	if err := c.inject("Touch", key); err != nil {
+		return err
+	}
+	return c.client.Touch(key, seconds)
+}
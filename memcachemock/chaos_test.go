@@ -0,0 +1,139 @@
+package memcachemock
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+// chaosScenario gets and sets n keys, returning the errors
+func chaosScenario(client Client, n int) []error {
+	var errs []error
+	for i := 0; i < n; i++ {
+		key := "key:" + strconv.Itoa(i)
+		errs = append(errs, client.Set(&memcache.Item{Key: key, Value: []byte("value")}))
+		_, err := client.Get(key)
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func TestChaos_NoRules(t *testing.T) {
+	a := assert.New(t)
+	fake := NewFake()
+	chaos := NewChaos(fake, 1)
+	for _, err := range chaosScenario(chaos, 50) {
+		a.NoError(err)
+	}
+	a.Empty(chaos.Faults())
+	a.Equal("no faults injected", chaos.Report())
+}
+
+func TestChaos_SameSeedSameFaults(t *testing.T) {
+	a := assert.New(t)
+	run := func(seed int64) ([]error, []ChaosFault) {
+		chaos := NewChaos(NewFake(), seed).
+			FailAll(0.3, memcache.ErrServerError, memcache.ErrNoServers, ErrTimeout)
+		return chaosScenario(chaos, 100), chaos.Faults()
+	}
+	errs1, faults1 := run(42)
+	errs2, faults2 := run(42)
+	a.Equal(errs1, errs2)
+	a.Equal(faults1, faults2)
+	a.NotEmpty(faults1)
+	errs3, _ := run(43)
+	a.NotEqual(errs1, errs3)
+}
+
+func TestChaos_Rate(t *testing.T) {
+	a := assert.New(t)
+	chaos := NewChaos(NewFake(), 7).Fail("Get", 0.25)
+	failed := 0
+	for _, err := range chaosScenario(chaos, 1000) {
+		if err != nil {
+			a.ErrorIs(err, memcache.ErrServerError)
+			failed++
+		}
+	}
+	a.InDelta(250, failed, 50)
+	faults := chaos.Faults()
+	a.Len(faults, failed)
+	for _, f := range faults {
+		a.Equal("Get", f.Method)
+	}
+}
+
+func TestChaos_PerMethodRules(t *testing.T) {
+	a := assert.New(t)
+	chaos := NewChaos(NewFake(), 1).
+		FailAll(1, memcache.ErrNoServers).
+		Fail("Get", 1, memcache.ErrCacheMiss).
+		Fail("Set()", 0)
+	errs := chaosScenario(chaos, 3)
+	for i, err := range errs {
+		if i%2 == 0 {
+			a.NoError(err)
+		} else {
+			a.ErrorIs(err, memcache.ErrCacheMiss)
+		}
+	}
+	a.ErrorIs(chaos.Delete("key:0"), memcache.ErrNoServers)
+	_, err := chaos.Increment("counter", 1)
+	a.ErrorIs(err, memcache.ErrNoServers)
+	a.Len(chaos.Faults(), 5)
+}
+
+func TestChaos_FailedCallsDoNotReachClient(t *testing.T) {
+	a := assert.New(t)
+	fake := NewFake()
+	chaos := NewChaos(fake, 1).Fail("Set", 1)
+	a.ErrorIs(chaos.Set(&memcache.Item{Key: "foo", Value: []byte("bar")}), memcache.ErrServerError)
+	_, err := fake.Get("foo")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestChaos_WrapsMock(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.ExpectGet().WithKey("foo").WillReturnItem(&memcache.Item{Key: "foo", Value: []byte("bar")})
+	chaos := NewChaos(mock, 1).Fail("Delete", 1)
+	item, err := chaos.Get("foo")
+	a.NoError(err)
+	a.Equal([]byte("bar"), item.Value)
+	a.Error(chaos.Delete("foo"))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestChaos_Timeout(t *testing.T) {
+	a := assert.New(t)
+	chaos := NewChaos(NewFake(), 1).Fail("Ping", 1, ErrTimeout)
+	err := chaos.Ping()
+	var netErr net.Error
+	a.True(errors.As(err, &netErr))
+	a.True(netErr.Timeout())
+	a.ErrorIs(err, os.ErrDeadlineExceeded)
+}
+
+func TestChaos_Report(t *testing.T) {
+	a := assert.New(t)
+	chaos := NewChaos(NewFake(), 1).FailAll(1)
+	_, _ = chaos.GetMulti([]string{"a", "b"})
+	_ = chaos.Ping()
+	a.Equal([]ChaosFault{
+		{Call: 0, Method: "GetMulti", Key: "a,b", Err: memcache.ErrServerError},
+		{Call: 1, Method: "Ping", Err: memcache.ErrServerError},
+	}, chaos.Faults())
+	a.Equal("#0 GetMulti(\"a,b\") => error: memcache: server error\n#1 Ping() => error: memcache: server error", chaos.Report())
+}
+
+func TestChaos_InvalidRate(t *testing.T) {
+	a := assert.New(t)
+	chaos := NewChaos(NewFake(), 1)
+	a.Panics(func() { chaos.Fail("Get", 1.5) })
+	a.Panics(func() { chaos.FailAll(-0.1) })
+}
@@ -0,0 +1,25 @@
+package memcachemock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClock(t *testing.T) {
+	a := assert.New(t)
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	a.Equal(start, clock.Now())
+	clock.Advance(time.Minute)
+	a.Equal(start.Add(time.Minute), clock.Now())
+	clock.Set(start)
+	a.Equal(start, clock.Now())
+}
+
+func TestSystemClock(t *testing.T) {
+	a := assert.New(t)
+	before := time.Now()
+	a.False(systemClock{}.Now().Before(before))
+}
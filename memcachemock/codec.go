@@ -0,0 +1,183 @@
+package memcachemock
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Codec encodes the values stored in memcache items, and decodes them back.
+// Implement it to match and return values encoded another way than JSON and gob.
+// A Codec implementing fmt.Stringer is named by it in expectation strings and mismatch errors.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON encodes values with encoding/json.
+var JSON Codec = jsonCodec{}
+
+// Gob encodes values with encoding/gob.
+var Gob Codec = gobCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) String() string {
+	return "JSON"
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobCodec) String() string {
+	return "gob"
+}
+
+// CodecValue matches an item value which decodes with codec to a value equal to v.
+// The comparison is semantic: with JSON, both values are decoded to generic values, so that the order of the fields,
+// whitespace and the notation of numbers do not matter, while an extra or missing field, or a field named with
+// another case, does not match. Other codecs decode the item value to the type of v.
+// It panics if v cannot be encoded.
+func CodecValue(codec Codec, v interface{}) Matcher {
+	data := encode(codec, v)
+	decode := typedDecoder(codec, reflect.TypeOf(v))
+	if codec == JSON {
+		decode = decodeJSON
+	}
+	expected, err := decode(data)
+	if err != nil {
+		panic(fmt.Sprintf("memcachemock: cannot decode %s to %T: %v", codecName(codec), v, err))
+	}
+	return &matcherFunc{desc: fmt.Sprintf("%s value %s", codecName(codec), describeEncoded(codec, data, v)), fn: func(arg interface{}) bool {
+		b, ok := arg.([]byte)
+		if !ok {
+			return false
+		}
+		actual, err := decode(b)
+		if err != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, actual)
+	}}
+}
+
+// JSONValue matches an item value holding the JSON encoding of v, whatever the order of the fields and whitespace.
+func JSONValue(v interface{}) Matcher {
+	return CodecValue(JSON, v)
+}
+
+// GobValue matches an item value holding the gob encoding of v.
+func GobValue(v interface{}) Matcher {
+	return CodecValue(Gob, v)
+}
+
+// encodedItem returns an item with the given key, holding v encoded with codec.
+// It panics if v cannot be encoded.
+func encodedItem(codec Codec, key string, v interface{}) *memcache.Item {
+	return &memcache.Item{Key: key, Value: encode(codec, v)}
+}
+
+func encode(codec Codec, v interface{}) []byte {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("memcachemock: cannot encode %T with %s: %v", v, codecName(codec), err))
+	}
+	return data
+}
+
+// typedDecoder returns a function decoding data to a value of type typ, so that it can be compared with another decoded value.
+func typedDecoder(codec Codec, typ reflect.Type) func(data []byte) (interface{}, error) {
+	return func(data []byte) (interface{}, error) {
+		v := reflect.New(typ)
+		if err := codec.Unmarshal(data, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+}
+
+// decodeJSON decodes data to a generic value, numbers being normalized to exact fractions, e.g. 1.0 to 1.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level JSON value")
+	}
+	return normalizeJSON(v)
+}
+
+// jsonNumber is the exact value of a JSON number, e.g. "1" or "1/3", distinct from a JSON string
+type jsonNumber string
+
+// normalizeJSON replaces the numbers of a generic JSON value by their exact value
+func normalizeJSON(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(string(v))
+		if !ok {
+			return nil, fmt.Errorf("invalid JSON number %s", v)
+		}
+		return jsonNumber(r.RatString()), nil
+	case map[string]interface{}:
+		for key, value := range v {
+			n, err := normalizeJSON(value)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = n
+		}
+	case []interface{}:
+		for i, value := range v {
+			n, err := normalizeJSON(value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+	}
+	return v, nil
+}
+
+func codecName(codec Codec) string {
+	if s, ok := codec.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", codec)
+}
+
+// describeEncoded describes a value, as its encoding if it is readable
+func describeEncoded(codec Codec, data []byte, v interface{}) string {
+	if codec == JSON {
+		return string(data)
+	}
+	return fmt.Sprintf("%+v", v)
+}
@@ -0,0 +1,129 @@
+package memcachemock
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name  string
+	Age   int
+	Roles []string
+}
+
+func TestJSONValue(t *testing.T) {
+	a := assert.New(t)
+	m := JSONValue(user{Name: "gopher", Age: 13, Roles: []string{"admin"}})
+
+	a.True(m.Match([]byte(`{"Name":"gopher","Age":13,"Roles":["admin"]}`)))
+	a.True(m.Match([]byte(`{ "Roles": ["admin"], "Age": 13,
+		"Name": "gopher" }`)), "field order and whitespace do not matter")
+	a.False(m.Match([]byte(`{"Name":"gopher","Age":14,"Roles":["admin"]}`)))
+	a.False(m.Match([]byte(`not json`)))
+	a.False(m.Match("not bytes"))
+	a.Equal(`JSON value {"Name":"gopher","Age":13,"Roles":["admin"]}`, m.String())
+
+	m = JSONValue(map[string]interface{}{"count": 1, "tags": []string{"a"}})
+	a.True(m.Match([]byte(`{"tags":["a"],"count":1.0}`)), "the expected value is normalized")
+
+	a.False(m.Match([]byte(`{"tags":["a"],"count":"1"}`)), "a string is not a number")
+	a.True(JSONValue(uint64(1<<63+1)).Match([]byte(`9223372036854775809`)), "numbers are compared exactly")
+	a.False(JSONValue(uint64(1<<63+1)).Match([]byte(`9223372036854775808`)), "numbers are compared exactly")
+
+	a.Panics(func() { JSONValue(func() {}) })
+}
+
+func TestJSONValue_ComparesAllFields(t *testing.T) {
+	a := assert.New(t)
+	m := JSONValue(user{Name: "gopher", Age: 13, Roles: []string{"admin"}})
+
+	a.False(m.Match([]byte(`{"Name":"gopher","Age":13,"Roles":["admin"],"Password":"secret"}`)), "extra field")
+	a.False(m.Match([]byte(`{"NAME":"gopher","Age":13,"Roles":["admin"]}`)), "field name differing in case")
+	a.False(m.Match([]byte(`{"Name":"gopher","Age":13}`)), "missing field")
+	a.False(JSONValue(user{}).Match([]byte(`{}`)), "missing fields do not decode to zero values")
+	a.False(m.Match([]byte(`{"Name":"gopher","Age":13,"Roles":["admin"]} {}`)), "trailing data")
+}
+
+func TestGobValue(t *testing.T) {
+	a := assert.New(t)
+	m := GobValue(user{Name: "gopher", Age: 13})
+
+	a.True(m.Match(mustEncode(t, Gob, user{Name: "gopher", Age: 13})))
+	a.False(m.Match(mustEncode(t, Gob, user{Name: "gopher", Age: 14})))
+	a.False(m.Match([]byte("garbage")))
+	a.Equal("gob value {Name:gopher Age:13 Roles:[]}", m.String())
+}
+
+func mustEncode(t *testing.T, codec Codec, v interface{}) []byte {
+	data, err := codec.Marshal(v)
+	assert.NoError(t, err)
+	return data
+}
+
+// upperCodec is a custom codec storing strings in upper case
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return json.Marshal(map[string]string{"upper": s})
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*(v.(*string)) = m["upper"]
+	return nil
+}
+
+func TestWithJSONValue(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectSet().WithItemKey("user:1").WithJSONValue(user{Name: "gopher", Age: 13})
+	mock.ExpectAdd().WithGobValue(user{Name: "gopher"})
+	mock.ExpectReplace().WithCodecValue(upperCodec{}, "value")
+
+	err := mock.Set(&memcache.Item{Key: "user:1", Value: []byte(`{"Age": 13, "Name": "gopher", "Roles": null}`)})
+	a.NoError(err)
+	a.NoError(mock.Add(&memcache.Item{Key: "user:1", Value: mustEncode(t, Gob, user{Name: "gopher"})}))
+	err = mock.Replace(&memcache.Item{Key: "user:1", Value: []byte(`{"upper":"other"}`)})
+	if a.Error(err) {
+		a.Contains(err.Error(), "expected value memcachemock.upperCodec value value, but got value")
+	}
+	a.NoError(mock.Replace(&memcache.Item{Key: "user:1", Value: []byte(`{"upper":"value"}`)}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnJSON(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectGet().WithKey("user:1").WillReturnJSON("user:1", user{Name: "gopher"})
+	mock.ExpectGet().WithKey("user:2").WillReturnGob("user:2", user{Name: "gopher"})
+	mock.ExpectGet().WithKey("user:3").WillReturnCodec(upperCodec{}, "user:3", "value")
+
+	item, err := mock.Get("user:1")
+	a.NoError(err)
+	a.Equal("user:1", item.Key)
+	a.JSONEq(`{"Name":"gopher","Age":0,"Roles":null}`, string(item.Value))
+
+	item, err = mock.Get("user:2")
+	a.NoError(err)
+	var u user
+	a.NoError(Gob.Unmarshal(item.Value, &u))
+	a.Equal(user{Name: "gopher"}, u)
+
+	item, err = mock.Get("user:3")
+	a.NoError(err)
+	a.Equal(`{"upper":"value"}`, string(item.Value))
+	a.NoError(mock.ExpectationsWereMet())
+
+	a.Panics(func() { mock.ExpectGet().WillReturnCodec(upperCodec{}, "k", 1) })
+}
@@ -0,0 +1,135 @@
+package memcachemock
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests are meant to be run with the race detector: go test -race
+
+const goroutines = 100
+
+func TestConcurrentCalls_DoNotOverConsumeExpectations(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectGet().WithKey("foo").WillReturnItem(&memcache.Item{Key: "foo"}).Times(goroutines / 2)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded int
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mock.Get("foo"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	a.Equal(goroutines/2, succeeded)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Len(mock.CallsTo("Get"), goroutines)
+}
+
+func TestConcurrentCalls_InAnyOrder(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < goroutines; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		mock.ExpectSet().WithItemKey(key)
+		mock.ExpectGet().WithKey(key).WillReturnItem(&memcache.Item{Key: key})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			a.NoError(mock.Set(&memcache.Item{Key: key}))
+			item, err := mock.Get(key)
+			if a.NoError(err) {
+				a.Equal(key, item.Key)
+			}
+		}(fmt.Sprintf("key-%d", i))
+	}
+	wg.Wait()
+
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestConcurrentExpectAndCalls(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			mock.ExpectIncrement().WithKeyAndDelta("counter", AnyDelta()).WillReturnValue(1)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = mock.Increment("counter", 1)
+			_ = mock.ExpectationsWereMet()
+			_ = mock.Calls()
+		}()
+	}
+	wg.Wait()
+
+	// every call either consumed an expectation declared before it, or failed
+	var failed int
+	for _, call := range mock.CallsTo("Increment") {
+		if call.Err != nil {
+			failed++
+		}
+	}
+	for i := 0; i < failed; i++ {
+		_, err := mock.Increment("counter", 1)
+		a.NoError(err)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestConcurrentCalls_InOrder(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.MatchExpectationsInOrder(false)
+	first := mock.ExpectSet().WithItemKey("foo")
+	second := mock.ExpectGet().WithKey("foo")
+	second.Times(goroutines)
+	mock.InOrder(first, second)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs int
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mock.Get("foo"); err != nil {
+				mu.Lock()
+				errs++
+				mu.Unlock()
+			}
+		}()
+	}
+	a.NoError(mock.Set(&memcache.Item{Key: "foo"}))
+	wg.Wait()
+
+	// the calls made before Set are rejected, the others match
+	for i := 0; i < errs; i++ {
+		_, err := mock.Get("foo")
+		a.NoError(err)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
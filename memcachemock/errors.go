@@ -0,0 +1,69 @@
+package memcachemock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnmetExpectationsError is returned by ExpectationsWereMet.
+// It lists every required expectation which was not called enough times,
+// and every expectation which was called while it should never be.
+type UnmetExpectationsError struct {
+	Unmet []UnmetExpectation
+}
+
+// UnmetExpectation is an expectation which was not fully met.
+type UnmetExpectation struct {
+	Expectation Expectation
+	Called      uint // how many times the expectation was matched
+	Min         uint // how many times the expectation should have been matched at least
+	Max         uint // how many times the expectation could have been matched at most, math.MaxUint meaning no limit
+}
+
+// String returns string representation
+func (u UnmetExpectation) String() string {
+	switch {
+	case u.Called > u.Max && u.Max == 0:
+		return fmt.Sprintf("%s\t- called %s, but must never be called\n", u.Expectation, times(u.Called))
+	case u.Called > u.Max:
+		return fmt.Sprintf("%s\t- called %s, but at most %s expected\n", u.Expectation, times(u.Called), times(u.Max))
+	}
+	return fmt.Sprintf("%s\t- called %s, expected %s\n", u.Expectation, times(u.Called), describeCardinality(u.Min, u.Max))
+}
+
+// Error returns numbered summaries of the expectations which were not called enough times,
+// and of those which were called too many times, e.g. an expectation which must never be called.
+func (e *UnmetExpectationsError) Error() string {
+	var missing, excess []UnmetExpectation
+	for _, u := range e.Unmet {
+		if u.Called > u.Max {
+			excess = append(excess, u)
+		} else {
+			missing = append(missing, u)
+		}
+	}
+	w := new(strings.Builder)
+	writeUnmet(w, missing, "there is a remaining expectation which was not matched:",
+		"there are %d remaining expectations which were not matched:")
+	writeUnmet(w, excess, "there is an expectation which was called too many times:",
+		"there are %d expectations which were called too many times:")
+	return w.String()
+}
+
+// writeUnmet writes a numbered list of the expectations, under the heading matching their number
+func writeUnmet(w *strings.Builder, unmet []UnmetExpectation, one, many string) {
+	if len(unmet) == 0 {
+		return
+	}
+	if w.Len() > 0 {
+		w.WriteString("\n")
+	}
+	if len(unmet) == 1 {
+		fmt.Fprint(w, one)
+	} else {
+		fmt.Fprintf(w, many, len(unmet))
+	}
+	for i, u := range unmet {
+		fmt.Fprintf(w, "\n%d. %s", i+1, strings.TrimSuffix(u.String(), "\n"))
+	}
+}
@@ -2,9 +2,11 @@ package memcachemock
 
 import (
 	"fmt"
-	"reflect"
+	"math"
+	"math/rand"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 )
@@ -12,9 +14,18 @@ import (
 // an Expectation interface
 type Expectation interface {
 	error() error
-	required() bool
-	fulfilled() bool
-	fulfill()
+	satisfied() bool
+	saturated() bool
+	fulfill() uint
+	setCalled(n uint)
+	clone() Expectation
+	calls() (called, min, max uint)
+	latency() (delay time.Duration, unblock <-chan struct{})
+	sideEffects() (do []func(CallArgs), panics bool, panicValue interface{})
+	prerequisites() []Expectation
+	addPrerequisite(Expectation)
+	anyOrderGroup() *anyOrderGroup
+	setAnyOrderGroup(*anyOrderGroup)
 	sync.Locker
 	fmt.Stringer
 }
@@ -22,42 +33,201 @@ type Expectation interface {
 type CallModifier interface {
 	Maybe() CallModifier
 	Times(n uint) CallModifier
+	AtLeast(n uint) CallModifier
+	AtMost(n uint) CallModifier
+	Between(min, max uint) CallModifier
+	AnyTimes() CallModifier
+	Never() CallModifier
+	WillDelayFor(d time.Duration) CallModifier
+	WillDelayBetween(min, max time.Duration) CallModifier
+	WillBlockUntil(ch <-chan struct{}) CallModifier
+	WillPanic(v interface{}) CallModifier
+	Do(fn func(args CallArgs)) CallModifier
 	WillReturnError(err error)
 }
 
+// unlimitedCalls is the maximum number of calls of an expectation without upper bound
+const unlimitedCalls uint = math.MaxUint
+
 // commonExpectation struct
 // satisfies the Expectation interface
 type commonExpectation struct {
-	triggered    uint  // how many times method was called
-	err          error // should method return error
-	optional     bool  // can method be skipped
-	plannedCalls uint  // how many sequentional calls should be made
+	triggered  uint             // how many times method was called
+	err        error            // should method return error
+	optional   bool             // can method be skipped
+	bounded    bool             // whether minCalls and maxCalls were set, otherwise exactly one call is expected
+	minCalls   uint             // how many calls are required
+	maxCalls   uint             // how many calls are allowed, unlimitedCalls meaning no limit
+	delay      time.Duration    // how long the method takes to return
+	jitter     time.Duration    // random duration up to which the delay may be extended
+	unblock    <-chan struct{}  // the method returns once it is closed, if set
+	responses  []response       // responses of the first calls, queued with Then or WillReturnSequence
+	server     string           // address of the server the key should be routed to, if set
+	do         []func(CallArgs) // side effects run on each call
+	panics     bool             // whether the method panics with panicValue
+	panicValue interface{}
+	after      []Expectation  // expectations which must be met before this one
+	group      *anyOrderGroup // expectations which may be met in any order with this one
 	sync.Mutex
 }
 
+// anyOrderGroup identifies expectations declared with InAnyOrder.
+// It is not empty, as pointers to distinct zero-size values may be equal.
+type anyOrderGroup struct {
+	_ byte
+}
+
+// response is the value and error returned by a call
+type response struct {
+	value interface{}
+	err   error
+}
+
 func (e *commonExpectation) error() error {
 	return e.err
 }
 
-func (e *commonExpectation) required() bool {
-	return !e.optional
+// satisfied tells whether the expectation was called enough times, or is optional
+func (e *commonExpectation) satisfied() bool {
+	called, min, _ := e.calls()
+	return called >= min
 }
 
-func (e *commonExpectation) fulfilled() bool {
-	maxPlannedCalls := uint(1)
-	if e.plannedCalls > maxPlannedCalls {
-		maxPlannedCalls = e.plannedCalls
-	}
-	return e.triggered >= maxPlannedCalls
+// saturated tells whether the expectation cannot be called anymore
+func (e *commonExpectation) saturated() bool {
+	called, _, max := e.calls()
+	return called >= max
 }
 
-func (e *commonExpectation) fulfill() {
+// fulfill counts a call, and returns how many calls were made before it
+func (e *commonExpectation) fulfill() uint {
 	e.triggered++
+	return e.triggered - 1
+}
+
+// setCalled sets how many times the method was called
+func (e *commonExpectation) setCalled(n uint) {
+	e.triggered = n
+}
+
+// copyTo copies the expectation to c, except its prerequisites and group,
+// so that changing either does not change the other
+func (e *commonExpectation) copyTo(c *commonExpectation) {
+	c.triggered = e.triggered
+	c.err = e.err
+	c.optional = e.optional
+	c.bounded = e.bounded
+	c.minCalls = e.minCalls
+	c.maxCalls = e.maxCalls
+	c.delay = e.delay
+	c.jitter = e.jitter
+	c.unblock = e.unblock
+	c.responses = append([]response(nil), e.responses...)
+	c.server = e.server
+	c.do = append(([]func(CallArgs))(nil), e.do...)
+	c.panics = e.panics
+	c.panicValue = e.panicValue
+}
+
+// calls returns how many times the method was called, and the minimum and maximum number of calls awaited
+func (e *commonExpectation) calls() (called, min, max uint) {
+	min, max = 1, 1
+	if len(e.responses) > 0 {
+		min, max = uint(len(e.responses))+1, uint(len(e.responses))+1
+	}
+	if e.bounded {
+		min, max = e.minCalls, e.maxCalls
+	}
+	if e.optional {
+		min = 0
+	}
+	return e.triggered, min, max
+}
+
+// latency returns how long the method should take to return, jitter included,
+// and the channel it should wait for, if any
+func (e *commonExpectation) latency() (time.Duration, <-chan struct{}) {
+	delay := e.delay
+	if e.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(e.jitter) + 1))
+	}
+	return delay, e.unblock
+}
+
+// response returns the value and error of the call made after n others.
+// value is the current response of the expectation, returned once the queued responses are exhausted.
+func (e *commonExpectation) response(n uint, value interface{}) (interface{}, error) {
+	if n < uint(len(e.responses)) {
+		return e.responses[n].value, e.responses[n].err
+	}
+	return value, e.err
+}
+
+// queueResponse queues the current response, made of value and the error set,
+// so that the next response can be set.
+func (e *commonExpectation) queueResponse(value interface{}) {
+	e.responses = append(e.responses, response{value: value, err: e.err})
+	e.err = nil
+}
+
+// setSequence replaces the queued responses, converting them with convert.
+// The last response becomes the current one, and is returned.
+func (e *commonExpectation) setSequence(responses []interface{}, convert func(interface{}) (interface{}, bool)) interface{} {
+	e.responses = nil
+	e.err = nil
+	var value interface{}
+	for i, r := range responses {
+		if i > 0 {
+			e.queueResponse(value)
+			value = nil
+		}
+		switch v := r.(type) {
+		case nil:
+		case error:
+			e.err = v
+		default:
+			var ok bool
+			if value, ok = convert(v); !ok {
+				panic(fmt.Sprintf("memcachemock: unexpected response type %T", r))
+			}
+		}
+	}
+	return value
+}
+
+// sideEffects returns the functions to run on each call, and the value the method panics with, if any
+func (e *commonExpectation) sideEffects() ([]func(CallArgs), bool, interface{}) {
+	return e.do, e.panics, e.panicValue
+}
+
+func (e *commonExpectation) prerequisites() []Expectation {
+	return e.after
+}
+
+func (e *commonExpectation) addPrerequisite(ex Expectation) {
+	e.after = append(e.after, ex)
+}
+
+func (e *commonExpectation) anyOrderGroup() *anyOrderGroup {
+	return e.group
+}
+
+func (e *commonExpectation) setAnyOrderGroup(g *anyOrderGroup) {
+	e.group = g
+}
+
+// setCalls sets the minimum and maximum number of calls awaited
+func (e *commonExpectation) setCalls(min, max uint) {
+	e.bounded = true
+	e.minCalls = min
+	e.maxCalls = max
 }
 
 // Maybe allows the expected method call to be optional.
 // Not calling an optional method will not cause an error while asserting expectations
 func (e *commonExpectation) Maybe() CallModifier {
+	e.Lock()
+	defer e.Unlock()
 	e.optional = true
 	return e
 }
@@ -65,12 +235,115 @@ func (e *commonExpectation) Maybe() CallModifier {
 // Times indicates that the expected method should only fire the indicated number of times.
 // Zero value is ignored and means the same as one.
 func (e *commonExpectation) Times(n uint) CallModifier {
-	e.plannedCalls = n
+	e.Lock()
+	defer e.Unlock()
+	if n == 0 {
+		n = 1
+	}
+	e.setCalls(n, n)
+	return e
+}
+
+// AtLeast indicates that the expected method should fire at least n times, with no upper limit.
+func (e *commonExpectation) AtLeast(n uint) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.setCalls(n, unlimitedCalls)
+	return e
+}
+
+// AtMost indicates that the expected method may fire up to n times, including not at all.
+func (e *commonExpectation) AtMost(n uint) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.setCalls(0, n)
+	return e
+}
+
+// Between indicates that the expected method should fire from min to max times.
+// It panics if min is greater than max.
+func (e *commonExpectation) Between(min, max uint) CallModifier {
+	if min > max {
+		panic(fmt.Sprintf("memcachemock: Between min %d is greater than max %d", min, max))
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.setCalls(min, max)
+	return e
+}
+
+// AnyTimes indicates that the expected method may fire any number of times, including not at all.
+func (e *commonExpectation) AnyTimes() CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.setCalls(0, unlimitedCalls)
+	return e
+}
+
+// Never indicates that the expected method should not fire.
+// A matching call fails, and is reported by ExpectationsWereMet, unless it matches another expectation:
+// Never may be used as a catch-all after more specific expectations.
+func (e *commonExpectation) Never() CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.setCalls(0, 0)
+	return e
+}
+
+// WillDelayFor delays the return of the expected method by d, to simulate a slow cache.
+func (e *commonExpectation) WillDelayFor(d time.Duration) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.delay = d
+	e.jitter = 0
+	return e
+}
+
+// WillDelayBetween delays the return of the expected method by a random duration from min to max.
+// It panics if min is greater than max.
+func (e *commonExpectation) WillDelayBetween(min, max time.Duration) CallModifier {
+	if min > max {
+		panic(fmt.Sprintf("memcachemock: WillDelayBetween min %s is greater than max %s", min, max))
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.delay = min
+	e.jitter = max - min
+	return e
+}
+
+// WillBlockUntil blocks the expected method until ch is closed, or receives a value.
+// Any delay set with WillDelayFor or WillDelayBetween starts once ch is unblocked.
+func (e *commonExpectation) WillBlockUntil(ch <-chan struct{}) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.unblock = ch
+	return e
+}
+
+// WillPanic makes the expected method panic with v, e.g. to test recovery middleware.
+// The call still counts as matched for ExpectationsWereMet.
+func (e *commonExpectation) WillPanic(v interface{}) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.panics = true
+	e.panicValue = v
+	return e
+}
+
+// Do runs fn with the arguments of each call matching the expectation, before the method returns or panics.
+// Several functions run in the order they were given.
+func (e *commonExpectation) Do(fn func(args CallArgs)) CallModifier {
+	e.Lock()
+	defer e.Unlock()
+	e.do = append(e.do, fn)
 	return e
 }
 
 // WillReturnError allows to set an error for the expected method.
 func (e *commonExpectation) WillReturnError(err error) {
+	e.Lock()
+	defer e.Unlock()
 	e.err = err
 }
 
@@ -80,45 +353,149 @@ func (e *commonExpectation) String() string {
 	if e.err != nil {
 		fmt.Fprintf(w, "\t- returns error: %v\n", e.err)
 	}
+	if e.server != "" {
+		fmt.Fprintf(w, "\t- is on server: %s\n", e.server)
+	}
 	if e.optional {
 		fmt.Fprint(w, "\t- execution is optional\n")
 	}
-	if e.plannedCalls > 0 {
-		fmt.Fprintf(w, "\t- execution calls awaited: %d\n", e.plannedCalls)
+	if e.bounded {
+		fmt.Fprintf(w, "\t- execution calls awaited: %s\n", describeCardinality(e.minCalls, e.maxCalls))
+	}
+	if len(e.do) > 0 {
+		fmt.Fprintf(w, "\t- runs %d side effects\n", len(e.do))
+	}
+	if e.panics {
+		fmt.Fprintf(w, "\t- panics with: %v\n", e.panicValue)
+	}
+	if len(e.responses) > 0 {
+		fmt.Fprintf(w, "\t- responds with a sequence of %d responses\n", len(e.responses)+1)
+	}
+	if e.unblock != nil {
+		fmt.Fprint(w, "\t- blocks until unblocked\n")
+	}
+	if e.jitter > 0 {
+		fmt.Fprintf(w, "\t- delays between %s and %s\n", e.delay, e.delay+e.jitter)
+	} else if e.delay > 0 {
+		fmt.Fprintf(w, "\t- delays for %s\n", e.delay)
 	}
 	return w.String()
 }
 
+// describeCardinality describes a number of calls between min and max
+func describeCardinality(min, max uint) string {
+	switch {
+	case max == 0:
+		return "never"
+	case min == max:
+		return times(min)
+	case max == unlimitedCalls && min == 0:
+		return "any number of times"
+	case max == unlimitedCalls:
+		return "at least " + times(min)
+	case min == 0:
+		return "at most " + times(max)
+	}
+	return fmt.Sprintf("between %d and %s", min, times(max))
+}
+
+func times(n uint) string {
+	if n == 1 {
+		return "1 time"
+	}
+	return fmt.Sprintf("%d times", n)
+}
+
 // keyBasedExpectation is a base class that adds a key matching logic
 type keyBasedExpectation struct {
-	expectedKey string
+	expectedKey Matcher
+}
+
+func (e *keyBasedExpectation) keyMatcher() Matcher {
+	if e.expectedKey == nil {
+		return Eq("")
+	}
+	return e.expectedKey
 }
 
 func (e *keyBasedExpectation) keyMatches(key string) error {
-	if key != e.expectedKey {
-		return fmt.Errorf("expected key %s, but got key %s", e.expectedKey, key)
+	if !e.keyMatcher().Match(key) {
+		return fmt.Errorf("expected key %s, but got key %s", e.keyMatcher(), key)
 	}
 	return nil
 }
 
 // keysBasedExpectation is a base class that adds keys matching logic
 type keysBasedExpectation struct {
-	expectedKeys []string
+	expectedKeys Matcher
 }
 
-func (e *keysBasedExpectation) keysMatch(keys []string) error {
-	if len(keys) != len(e.expectedKeys) {
-		return fmt.Errorf("expected keys %v, but got keys %v", e.expectedKeys, keys)
+func (e *keysBasedExpectation) keysMatcher() Matcher {
+	if e.expectedKeys == nil {
+		return Eq([]string(nil))
 	}
-	if !reflect.DeepEqual(keys, e.expectedKeys) {
-		return fmt.Errorf("expected keys %v, but got keys %v", e.expectedKeys, keys)
+	return e.expectedKeys
+}
+
+func (e *keysBasedExpectation) keysMatch(keys []string) error {
+	if !e.keysMatcher().Match(keys) {
+		return fmt.Errorf("expected keys %s, but got keys %v", e.keysMatcher(), keys)
 	}
 	return nil
 }
 
 // itemBasedExpectation is a base class that adds an memcache.Item matching logic
 type itemBasedExpectation struct {
-	expectedItem *memcache.Item
+	expectedItem Matcher // nil means that a nil item is expected
+}
+
+// itemFields returns the ItemMatcher used by the WithItem* builders.
+// It panics if the item is expected with another Matcher, which the fields cannot be combined with.
+func (e *itemBasedExpectation) itemFields() *ItemMatcher {
+	switch m := e.expectedItem.(type) {
+	case nil:
+		m2 := MatchItem()
+		e.expectedItem = m2
+		return m2
+	case *ItemMatcher:
+		return m
+	default:
+		panic(fmt.Sprintf("memcachemock: item fields cannot be set on an item expected with %s, use an ItemMatcher instead", m))
+	}
+}
+
+// clone returns a copy of the item expectation, so that the WithItem* builders of either do not change the other
+func (e itemBasedExpectation) clone() itemBasedExpectation {
+	if m, ok := e.expectedItem.(*ItemMatcher); ok {
+		c := *m
+		return itemBasedExpectation{expectedItem: &c}
+	}
+	return e
+}
+
+func (e *itemBasedExpectation) setItem(item interface{}) {
+	switch it := item.(type) {
+	case nil:
+		e.expectedItem = nil
+	case *memcache.Item:
+		if it == nil {
+			e.expectedItem = nil
+			return
+		}
+		e.expectedItem = matchItemFields(it)
+	case *ItemMatcher:
+		if it == nil {
+			e.expectedItem = nil
+			return
+		}
+		// copied, so that the WithItem* builders do not change the matcher of other expectations
+		m := *it
+		e.expectedItem = &m
+	case Matcher:
+		e.expectedItem = it
+	default:
+		panic(fmt.Sprintf("memcachemock: item must be a *memcache.Item or a Matcher, got %T", item))
+	}
 }
 
 func (e *itemBasedExpectation) itemMatches(item *memcache.Item) error {
@@ -128,44 +505,88 @@ func (e *itemBasedExpectation) itemMatches(item *memcache.Item) error {
 	if e.expectedItem == nil && item != nil {
 		return fmt.Errorf("did not expect item, but got item with key %s", item.Key)
 	}
-	if item.Key != e.expectedItem.Key {
-		return fmt.Errorf("expected item with key %s, but got item with key %s", e.expectedItem.Key, item.Key)
-	}
-	if string(item.Value) != string(e.expectedItem.Value) {
-		return fmt.Errorf("expected item with value %s, but got item with value %s", string(e.expectedItem.Value), string(item.Value))
+	if item == nil {
+		return fmt.Errorf("expected %s, but got no item", e.expectedItem)
 	}
-	if item.Flags != e.expectedItem.Flags {
-		return fmt.Errorf("expected item with flags %d, but got item with flags %d", e.expectedItem.Flags, item.Flags)
+	m, ok := e.expectedItem.(*ItemMatcher)
+	if !ok {
+		if !e.expectedItem.Match(item) {
+			return fmt.Errorf("expected %s, but got item with key %s", e.expectedItem, item.Key)
+		}
+		return nil
 	}
-	if item.Expiration != e.expectedItem.Expiration {
-		return fmt.Errorf("expected item with expiration %d, but got item with expiration %d", e.expectedItem.Expiration, item.Expiration)
+	diffs := m.mismatches(item)
+	if len(diffs) == 1 {
+		return fmt.Errorf("item with key %s does not match: %s", item.Key, diffs[0])
 	}
-	if item.CasID != e.expectedItem.CasID {
-		return fmt.Errorf("expected item with casID %d, but got item with casID %d", e.expectedItem.CasID, item.CasID)
+	if len(diffs) > 1 {
+		return fmt.Errorf("item with key %s does not match:\n\t- %s", item.Key, strings.Join(diffs, "\n\t- "))
 	}
 	return nil
 }
 
+// String returns string representation
+func (e *itemBasedExpectation) String() string {
+	if e.expectedItem == nil {
+		return ""
+	}
+	m, ok := e.expectedItem.(*ItemMatcher)
+	if !ok {
+		return fmt.Sprintf("\t- is with %s\n", e.expectedItem)
+	}
+	msg := "\t- is with item with any key\n"
+	if m.key != nil {
+		msg = fmt.Sprintf("\t- is with item with key: %s\n", m.key)
+	}
+	if m.value != nil {
+		msg += fmt.Sprintf("\t- and with value: %s\n", m.value)
+	}
+	if m.flags != nil {
+		msg += fmt.Sprintf("\t- and with flags: %s\n", m.flags)
+	}
+	if m.expiration != nil {
+		msg += fmt.Sprintf("\t- and expiration date: %s\n", m.expiration)
+	}
+	if m.casID != nil {
+		msg += fmt.Sprintf("\t- and with casID: %s\n", m.casID)
+	}
+	return msg
+}
+
 // deltaBasedExpectation is a base class that adds a delta matching logic
 type deltaBasedExpectation struct {
-	expectedDelta uint64
+	expectedDelta Matcher
+}
+
+func (e *deltaBasedExpectation) deltaMatcher() Matcher {
+	if e.expectedDelta == nil {
+		return Eq(uint64(0))
+	}
+	return e.expectedDelta
 }
 
 func (e *deltaBasedExpectation) deltaMatches(delta uint64) error {
-	if delta != e.expectedDelta {
-		return fmt.Errorf("expected call with delta %d, but got delta %d", e.expectedDelta, delta)
+	if !e.deltaMatcher().Match(delta) {
+		return fmt.Errorf("expected call with delta %s, but got delta %d", e.deltaMatcher(), delta)
 	}
 	return nil
 }
 
 // secondsBasedExpectation is a base class that adds a seconds matching logic
 type secondsBasedExpectation struct {
-	expectedSeconds int32
+	expectedSeconds Matcher
+}
+
+func (e *secondsBasedExpectation) secondsMatcher() Matcher {
+	if e.expectedSeconds == nil {
+		return Eq(int32(0))
+	}
+	return e.expectedSeconds
 }
 
 func (e *secondsBasedExpectation) secondsMatch(seconds int32) error {
-	if seconds != e.expectedSeconds {
-		return fmt.Errorf("expected call with seconds: %d, but got seconds: %d", e.expectedSeconds, seconds)
+	if !e.secondsMatcher().Match(seconds) {
+		return fmt.Errorf("expected call with seconds: %s, but got seconds: %d", e.secondsMatcher(), seconds)
 	}
 	return nil
 }
@@ -176,260 +597,1157 @@ func (e *secondsBasedExpectation) secondsMatch(seconds int32) error {
 type ExpectedAdd struct {
 	commonExpectation
 	itemBasedExpectation
+	respond func(item *memcache.Item) error
 }
 
 // WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Add().
 // If at least one field does not match, it will return an error.
-func (e *ExpectedAdd) WithItem(item *memcache.Item) *ExpectedAdd {
-	e.expectedItem = item
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedAdd) WithItem(item interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedAdd) String() string {
-	msg := "ExpectedAdd => expecting call to Add():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
-	}
-	return msg + e.commonExpectation.String()
+// WithItemKey will match only the key of the item used when calling memcache.Client.Add().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedAdd) WithItemKey(key interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
 }
 
-// ExpectedAppend is used to manage *memcache.Client.Append expectations
-type ExpectedAppend struct {
-	commonExpectation
-	itemBasedExpectation
+// WithItemValue will match only the value of the item used when calling memcache.Client.Add().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedAdd) WithItemValue(value interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
 }
 
-// WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Append().
-// If at least one field does not match, it will return an error.
-func (e *ExpectedAppend) WithItem(item *memcache.Item) *ExpectedAppend {
-	e.expectedItem = item
+// WithJSONValue will match only the value of the item used when calling memcache.Client.Add(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedAdd) WithJSONValue(v interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedAppend) String() string {
-	msg := "ExpectedAppend => expecting call to Append():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
-	}
-	return msg + e.commonExpectation.String()
+// WithGobValue will match only the value of the item used when calling memcache.Client.Add(),
+// if it holds the gob encoding of v.
+func (e *ExpectedAdd) WithGobValue(v interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
+	return e
 }
 
-// ExpectedClose is used to manage *memcache.Client.Close expectations
-type ExpectedClose struct {
-	commonExpectation
+// WithCodecValue will match only the value of the item used when calling memcache.Client.Add(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedAdd) WithCodecValue(codec Codec, v interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
+	return e
 }
 
-// String returns string representation
-func (e *ExpectedClose) String() string {
-	return "ExpectedClose => expecting call to Close()\n" + e.commonExpectation.String()
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.Add().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedAdd) WithItemFlags(flags interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
 }
 
-// ExpectedCompareAndSwap is used to manage *memcache.Client.CompareAndSwap expectations
-type ExpectedCompareAndSwap struct {
-	commonExpectation
-	itemBasedExpectation
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.Add().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedAdd) WithItemExpiration(expiration interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
 }
 
-// WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.CompareAndSwap().
-// If at least one field does not match, it will return an error.
-func (e *ExpectedCompareAndSwap) WithItem(item *memcache.Item) *ExpectedCompareAndSwap {
-	e.expectedItem = item
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedAdd) WithItemExpirationBetween(min, max int32) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedCompareAndSwap) String() string {
-	msg := "ExpectedCompareAndSwap => expecting call to CompareAndSwap():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
-	}
-	return msg + e.commonExpectation.String()
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.Add().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedAdd) WithItemCasID(casID interface{}) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
+	return e
 }
 
-// ExpectedDecrement is used to manage *memcache.Client.Decrement expectations
-type ExpectedDecrement struct {
-	commonExpectation
-	keyBasedExpectation
-	deltaBasedExpectation
-	value uint64
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedAdd) IgnoringExpiration() *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
 }
 
-// WithKeyAndDelta will match given expected key and delta value to actual key and delta used when calling memcache.Client.Decrement().
-// If at least one parameter does not match, it will return an error.
-func (e *ExpectedDecrement) WithKeyAndDelta(key string, delta uint64) *ExpectedDecrement {
-	e.expectedKey = key
-	e.expectedDelta = delta
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedAdd) IgnoringCasID() *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
 	return e
 }
 
-// WillReturnValue specifies the value that will be returned when calling memcache.Client.Decrement().
-func (e *ExpectedDecrement) WillReturnValue(value uint64) *ExpectedDecrement {
-	e.value = value
+// WillRespond specifies a callback computing the response of memcache.Client.Add() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedAdd) WillRespond(fn func(item *memcache.Item) error) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedDecrement) String() string {
-	msg := "ExpectedDecrement => expecting call to Decrement():\n"
-	msg += fmt.Sprintf("\t- is with key: %s\n", e.expectedKey)
-	msg += fmt.Sprintf("\t- and with delta: %d\n", e.expectedDelta)
-	return msg + e.commonExpectation.String()
+// OnServer will match only calls to memcache.Client.Add() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedAdd) OnServer(addr string) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
 }
 
-// ExpectedDelete is used to manage *memcache.Client.Delete expectations
-type ExpectedDelete struct {
-	commonExpectation
-	keyBasedExpectation
+// Then queues the response set so far, returned by the next call to memcache.Client.Add().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedAdd) Then() *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
 }
 
-// WithKey will match given expected key to actual key used when calling memcache.Client.Delete().
-// if the keys do not match, it will return an error.
-func (e *ExpectedDelete) WithKey(key string) *ExpectedDelete {
-	e.expectedKey = key
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Add(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedAdd) WillReturnSequence(errs ...error) *ExpectedAdd {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
 	return e
 }
 
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedAdd) clone() Expectation {
+	c := &ExpectedAdd{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
 // String returns string representation
-func (e *ExpectedDelete) String() string {
-	msg := "ExpectedDelete => expecting call to Delete():\n"
-	msg += fmt.Sprintf("\t- is with key: %s\n", e.expectedKey)
+func (e *ExpectedAdd) String() string {
+	msg := "ExpectedAdd => expecting call to Add():\n"
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
 	return msg + e.commonExpectation.String()
 }
 
-// ExpectedDeleteAll is used to manage *memcache.Client.DeleteAll expectations
-type ExpectedDeleteAll struct {
+// ExpectedAppend is used to manage *memcache.Client.Append expectations
+type ExpectedAppend struct {
 	commonExpectation
+	itemBasedExpectation
+	respond func(item *memcache.Item) error
 }
 
-// String returns string representation
-func (e *ExpectedDeleteAll) String() string {
-	return "ExpectedDeleteAll => expecting call to DeleteAll()\n" + e.commonExpectation.String()
+// WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Append().
+// If at least one field does not match, it will return an error.
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedAppend) WithItem(item interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
+	return e
 }
 
-// ExpectedFlushAll is used to manage *memcache.Client.FlushAll expectations
-type ExpectedFlushAll struct {
-	commonExpectation
+// WithItemKey will match only the key of the item used when calling memcache.Client.Append().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedAppend) WithItemKey(key interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
 }
 
-// String returns string representation
-func (e *ExpectedFlushAll) String() string {
-	return "ExpectedFlushAll => expecting call to FlushAll()\n" + e.commonExpectation.String()
+// WithItemValue will match only the value of the item used when calling memcache.Client.Append().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedAppend) WithItemValue(value interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
 }
 
-// ExpectedGet is used to manage *memcache.Client.Get expectations
-type ExpectedGet struct {
-	commonExpectation
-	keyBasedExpectation
-	item *memcache.Item
+// WithJSONValue will match only the value of the item used when calling memcache.Client.Append(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedAppend) WithJSONValue(v interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
+	return e
 }
 
-// WithKey will match given expected key to actual key used when calling memcache.Client.Get().
-// if the keys do not match, it will return an error.
-func (e *ExpectedGet) WithKey(key string) *ExpectedGet {
-	e.expectedKey = key
+// WithGobValue will match only the value of the item used when calling memcache.Client.Append(),
+// if it holds the gob encoding of v.
+func (e *ExpectedAppend) WithGobValue(v interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
 	return e
 }
 
-// WillReturnItem specifies the memcache.Item that will be returned when calling memcache.Client.Get().
-func (e *ExpectedGet) WillReturnItem(item *memcache.Item) *ExpectedGet {
-	e.item = item
+// WithCodecValue will match only the value of the item used when calling memcache.Client.Append(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedAppend) WithCodecValue(codec Codec, v interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedGet) String() string {
-	msg := "ExpectedGet => expecting call to Get():\n"
-	msg += fmt.Sprintf("\t- is with key: %s\n", e.expectedKey)
-	if e.item != nil {
-		msg += fmt.Sprintf("\t- returns item with key: %s\n", e.item.Key)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.item.Expiration)
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.item.Flags)
-	}
-	return msg + e.commonExpectation.String()
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.Append().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedAppend) WithItemFlags(flags interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
 }
 
-// ExpectedGetMulti is used to manage *memcache.Client.GetMulti expectations
-type ExpectedGetMulti struct {
-	commonExpectation
-	keysBasedExpectation
-	items map[string]*memcache.Item
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.Append().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedAppend) WithItemExpiration(expiration interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
 }
 
-// WithKeys will match given expected keys to actual keys used when calling memcache.Client.GetMulti().
-// If at least one of the keys does not match, it will return an error.
-func (e *ExpectedGetMulti) WithKeys(keys []string) *ExpectedGetMulti {
-	e.expectedKeys = keys
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedAppend) WithItemExpirationBetween(min, max int32) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
 	return e
 }
 
-// WillReturnItems specifies the map of memcache.Item that will be returned when calling memcache.Client.GetMulti().
-func (e *ExpectedGetMulti) WillReturnItems(items map[string]*memcache.Item) *ExpectedGetMulti {
-	e.items = items
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.Append().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedAppend) WithItemCasID(casID interface{}) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedGetMulti) String() string {
-	msg := "ExpectedGetMulti => expecting call to GetMulti():\n"
-	msg += fmt.Sprintf("\t- is with keys: %s\n", e.expectedKeys)
-	if e.items != nil {
-		msg += fmt.Sprintf("\t- returns items: %v\n", e.items)
-	}
-	return msg + e.commonExpectation.String()
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedAppend) IgnoringExpiration() *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
 }
 
-// ExpectedIncrement is used to manage *memcache.Client.Increment expectations
-type ExpectedIncrement struct {
-	commonExpectation
-	keyBasedExpectation
-	deltaBasedExpectation
-	value uint64
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedAppend) IgnoringCasID() *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
+	return e
 }
 
-// WithKeyAndDelta will match given expected key and delta value to actual key and delta used when calling memcache.Client.Increment().
-// If at least one parameter does not match, it will return an error.
-func (e *ExpectedIncrement) WithKeyAndDelta(key string, delta uint64) *ExpectedIncrement {
-	e.expectedKey = key
-	e.expectedDelta = delta
+// WillRespond specifies a callback computing the response of memcache.Client.Append() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedAppend) WillRespond(fn func(item *memcache.Item) error) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
 	return e
 }
 
-// WillReturnValue specifies the value that will be returned when calling memcache.Client.Increment().
-func (e *ExpectedIncrement) WillReturnValue(value uint64) *ExpectedIncrement {
-	e.value = value
+// OnServer will match only calls to memcache.Client.Append() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedAppend) OnServer(addr string) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
 	return e
 }
 
-// String returns string representation
-func (e *ExpectedIncrement) String() string {
-	msg := "ExpectedIncrement => expecting call to Increment():\n"
-	msg += fmt.Sprintf("\t- is with key: %s\n", e.expectedKey)
-	msg += fmt.Sprintf("\t- and with delta: %d\n", e.expectedDelta)
-	return msg + e.commonExpectation.String()
+// Then queues the response set so far, returned by the next call to memcache.Client.Append().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedAppend) Then() *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
 }
 
-// ExpectedPing is used to manage *memcache.Client.Ping expectations
-type ExpectedPing struct {
-	commonExpectation
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Append(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedAppend) WillReturnSequence(errs ...error) *ExpectedAppend {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedAppend) clone() Expectation {
+	c := &ExpectedAppend{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
 }
 
 // String returns string representation
-func (e *ExpectedPing) String() string {
+func (e *ExpectedAppend) String() string {
+	msg := "ExpectedAppend => expecting call to Append():\n"
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedClose is used to manage *memcache.Client.Close expectations
+type ExpectedClose struct {
+	commonExpectation
+	respond func() error
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Close().
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedClose) WillRespond(fn func() error) *ExpectedClose {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Close().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedClose) Then() *ExpectedClose {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Close(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedClose) WillReturnSequence(errs ...error) *ExpectedClose {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedClose) clone() Expectation {
+	c := &ExpectedClose{respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedClose) String() string {
+	msg := "ExpectedClose => expecting call to Close()\n"
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedCompareAndSwap is used to manage *memcache.Client.CompareAndSwap expectations
+type ExpectedCompareAndSwap struct {
+	commonExpectation
+	itemBasedExpectation
+	respond func(item *memcache.Item) error
+}
+
+// WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.CompareAndSwap().
+// If at least one field does not match, it will return an error.
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedCompareAndSwap) WithItem(item interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
+	return e
+}
+
+// WithItemKey will match only the key of the item used when calling memcache.Client.CompareAndSwap().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedCompareAndSwap) WithItemKey(key interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
+}
+
+// WithItemValue will match only the value of the item used when calling memcache.Client.CompareAndSwap().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedCompareAndSwap) WithItemValue(value interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
+}
+
+// WithJSONValue will match only the value of the item used when calling memcache.Client.CompareAndSwap(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedCompareAndSwap) WithJSONValue(v interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
+	return e
+}
+
+// WithGobValue will match only the value of the item used when calling memcache.Client.CompareAndSwap(),
+// if it holds the gob encoding of v.
+func (e *ExpectedCompareAndSwap) WithGobValue(v interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
+	return e
+}
+
+// WithCodecValue will match only the value of the item used when calling memcache.Client.CompareAndSwap(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedCompareAndSwap) WithCodecValue(codec Codec, v interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
+	return e
+}
+
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.CompareAndSwap().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedCompareAndSwap) WithItemFlags(flags interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
+}
+
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.CompareAndSwap().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedCompareAndSwap) WithItemExpiration(expiration interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
+}
+
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedCompareAndSwap) WithItemExpirationBetween(min, max int32) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
+	return e
+}
+
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.CompareAndSwap().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedCompareAndSwap) WithItemCasID(casID interface{}) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
+	return e
+}
+
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedCompareAndSwap) IgnoringExpiration() *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
+}
+
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedCompareAndSwap) IgnoringCasID() *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.CompareAndSwap() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedCompareAndSwap) WillRespond(fn func(item *memcache.Item) error) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.CompareAndSwap() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedCompareAndSwap) OnServer(addr string) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.CompareAndSwap().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedCompareAndSwap) Then() *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.CompareAndSwap(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedCompareAndSwap) WillReturnSequence(errs ...error) *ExpectedCompareAndSwap {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedCompareAndSwap) clone() Expectation {
+	c := &ExpectedCompareAndSwap{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedCompareAndSwap) String() string {
+	msg := "ExpectedCompareAndSwap => expecting call to CompareAndSwap():\n"
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedDecrement is used to manage *memcache.Client.Decrement expectations
+type ExpectedDecrement struct {
+	commonExpectation
+	keyBasedExpectation
+	deltaBasedExpectation
+	value   uint64
+	respond func(key string, delta uint64) (uint64, error)
+}
+
+// WithKeyAndDelta will match given expected key and delta value to actual key and delta used when calling memcache.Client.Decrement().
+// If at least one parameter does not match, it will return an error.
+// Both key and delta may be given as a Matcher, e.g. KeyPrefix or DeltaBetween.
+func (e *ExpectedDecrement) WithKeyAndDelta(key interface{}, delta interface{}) *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKey = toKeyMatcher(key)
+	e.expectedDelta = toDeltaMatcher(delta)
+	return e
+}
+
+// WillReturnValue specifies the value that will be returned when calling memcache.Client.Decrement().
+func (e *ExpectedDecrement) WillReturnValue(value uint64) *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	e.value = value
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Decrement() from the actual key and delta.
+// The callback result replaces any value or error set with the WillReturn* methods.
+func (e *ExpectedDecrement) WillRespond(fn func(key string, delta uint64) (uint64, error)) *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Decrement() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedDecrement) OnServer(addr string) *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Decrement().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedDecrement) Then() *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(e.value)
+	e.value = 0
+	return e
+}
+
+// WillReturnSequence sets the responses of successive calls to memcache.Client.Decrement().
+// Each response is a non-negative integer, an error, or nil for zero without error.
+// Unless the number of calls is set, one call per response is awaited. The last response is returned by any further call.
+// It panics on a response of another type.
+func (e *ExpectedDecrement) WillReturnSequence(responses ...interface{}) *ExpectedDecrement {
+	e.Lock()
+	defer e.Unlock()
+	value := e.setSequence(responses, func(v interface{}) (interface{}, bool) {
+		return toUint64(v)
+	})
+	e.value, _ = value.(uint64)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedDecrement) clone() Expectation {
+	c := &ExpectedDecrement{keyBasedExpectation: e.keyBasedExpectation, deltaBasedExpectation: e.deltaBasedExpectation, value: e.value, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedDecrement) String() string {
+	msg := "ExpectedDecrement => expecting call to Decrement():\n"
+	msg += fmt.Sprintf("\t- is with key: %s\n", e.keyMatcher())
+	msg += fmt.Sprintf("\t- and with delta: %s\n", e.deltaMatcher())
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedDelete is used to manage *memcache.Client.Delete expectations
+type ExpectedDelete struct {
+	commonExpectation
+	keyBasedExpectation
+	respond func(key string) error
+}
+
+// WithKey will match given expected key to actual key used when calling memcache.Client.Delete().
+// if the keys do not match, it will return an error.
+// The key may be given as a Matcher, e.g. AnyKey, KeyPrefix or KeyRegexp.
+func (e *ExpectedDelete) WithKey(key interface{}) *ExpectedDelete {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKey = toKeyMatcher(key)
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Delete() from the actual key.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedDelete) WillRespond(fn func(key string) error) *ExpectedDelete {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Delete() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedDelete) OnServer(addr string) *ExpectedDelete {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Delete().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedDelete) Then() *ExpectedDelete {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Delete(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedDelete) WillReturnSequence(errs ...error) *ExpectedDelete {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedDelete) clone() Expectation {
+	c := &ExpectedDelete{keyBasedExpectation: e.keyBasedExpectation, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedDelete) String() string {
+	msg := "ExpectedDelete => expecting call to Delete():\n"
+	msg += fmt.Sprintf("\t- is with key: %s\n", e.keyMatcher())
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedDeleteAll is used to manage *memcache.Client.DeleteAll expectations
+type ExpectedDeleteAll struct {
+	commonExpectation
+	respond func() error
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.DeleteAll().
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedDeleteAll) WillRespond(fn func() error) *ExpectedDeleteAll {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.DeleteAll().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedDeleteAll) Then() *ExpectedDeleteAll {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.DeleteAll(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedDeleteAll) WillReturnSequence(errs ...error) *ExpectedDeleteAll {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedDeleteAll) clone() Expectation {
+	c := &ExpectedDeleteAll{respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedDeleteAll) String() string {
+	msg := "ExpectedDeleteAll => expecting call to DeleteAll()\n"
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedFlushAll is used to manage *memcache.Client.FlushAll expectations
+type ExpectedFlushAll struct {
+	commonExpectation
+	respond func() error
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.FlushAll().
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedFlushAll) WillRespond(fn func() error) *ExpectedFlushAll {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.FlushAll().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedFlushAll) Then() *ExpectedFlushAll {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.FlushAll(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedFlushAll) WillReturnSequence(errs ...error) *ExpectedFlushAll {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedFlushAll) clone() Expectation {
+	c := &ExpectedFlushAll{respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedFlushAll) String() string {
+	msg := "ExpectedFlushAll => expecting call to FlushAll()\n"
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedGet is used to manage *memcache.Client.Get expectations
+type ExpectedGet struct {
+	commonExpectation
+	keyBasedExpectation
+	item    *memcache.Item
+	respond func(key string) (*memcache.Item, error)
+}
+
+// WithKey will match given expected key to actual key used when calling memcache.Client.Get().
+// if the keys do not match, it will return an error.
+// The key may be given as a Matcher, e.g. AnyKey, KeyPrefix or KeyRegexp.
+func (e *ExpectedGet) WithKey(key interface{}) *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKey = toKeyMatcher(key)
+	return e
+}
+
+// WillReturnItem specifies the memcache.Item that will be returned when calling memcache.Client.Get().
+func (e *ExpectedGet) WillReturnItem(item *memcache.Item) *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	e.item = item
+	return e
+}
+
+// WillReturnJSON will return an item with the given key, holding the JSON encoding of v.
+func (e *ExpectedGet) WillReturnJSON(key string, v interface{}) *ExpectedGet {
+	return e.WillReturnCodec(JSON, key, v)
+}
+
+// WillReturnGob will return an item with the given key, holding the gob encoding of v.
+func (e *ExpectedGet) WillReturnGob(key string, v interface{}) *ExpectedGet {
+	return e.WillReturnCodec(Gob, key, v)
+}
+
+// WillReturnCodec will return an item with the given key, holding v encoded with codec.
+func (e *ExpectedGet) WillReturnCodec(codec Codec, key string, v interface{}) *ExpectedGet {
+	return e.WillReturnItem(encodedItem(codec, key, v))
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Get() from the actual key.
+// The callback result replaces any item or error set with the WillReturn* methods.
+func (e *ExpectedGet) WillRespond(fn func(key string) (*memcache.Item, error)) *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Get() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedGet) OnServer(addr string) *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Get().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedGet) Then() *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(e.item)
+	e.item = nil
+	return e
+}
+
+// WillReturnSequence sets the responses of successive calls to memcache.Client.Get().
+// Each response is a *memcache.Item, an error, or nil for a nil item without error.
+// Unless the number of calls is set, one call per response is awaited. The last response is returned by any further call.
+// It panics on a response of another type.
+func (e *ExpectedGet) WillReturnSequence(responses ...interface{}) *ExpectedGet {
+	e.Lock()
+	defer e.Unlock()
+	value := e.setSequence(responses, func(v interface{}) (interface{}, bool) {
+		item, ok := v.(*memcache.Item)
+		return item, ok
+	})
+	e.item, _ = value.(*memcache.Item)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedGet) clone() Expectation {
+	c := &ExpectedGet{keyBasedExpectation: e.keyBasedExpectation, item: e.item, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedGet) String() string {
+	msg := "ExpectedGet => expecting call to Get():\n"
+	msg += fmt.Sprintf("\t- is with key: %s\n", e.keyMatcher())
+	if e.item != nil {
+		msg += fmt.Sprintf("\t- returns item with key: %s\n", e.item.Key)
+		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.item.Expiration)
+		msg += fmt.Sprintf("\t- and with flags: %d\n", e.item.Flags)
+	}
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedGetMulti is used to manage *memcache.Client.GetMulti expectations
+type ExpectedGetMulti struct {
+	commonExpectation
+	keysBasedExpectation
+	items   map[string]*memcache.Item
+	respond func(keys []string) (map[string]*memcache.Item, error)
+}
+
+// WithKeys will match given expected keys to actual keys used when calling memcache.Client.GetMulti().
+// If at least one of the keys does not match, it will return an error.
+// The keys may be given as a Matcher, e.g. AnyKeys or KeysInAnyOrder.
+func (e *ExpectedGetMulti) WithKeys(keys interface{}) *ExpectedGetMulti {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKeys = toKeysMatcher(keys)
+	return e
+}
+
+// WillReturnItems specifies the map of memcache.Item that will be returned when calling memcache.Client.GetMulti().
+func (e *ExpectedGetMulti) WillReturnItems(items map[string]*memcache.Item) *ExpectedGetMulti {
+	e.Lock()
+	defer e.Unlock()
+	e.items = items
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.GetMulti() from the actual keys.
+// The callback result replaces any items or error set with the WillReturn* methods.
+func (e *ExpectedGetMulti) WillRespond(fn func(keys []string) (map[string]*memcache.Item, error)) *ExpectedGetMulti {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.GetMulti().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedGetMulti) Then() *ExpectedGetMulti {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(e.items)
+	e.items = nil
+	return e
+}
+
+// WillReturnSequence sets the responses of successive calls to memcache.Client.GetMulti().
+// Each response is a map[string]*memcache.Item, an error, or nil for a nil map without error.
+// Unless the number of calls is set, one call per response is awaited. The last response is returned by any further call.
+// It panics on a response of another type.
+func (e *ExpectedGetMulti) WillReturnSequence(responses ...interface{}) *ExpectedGetMulti {
+	e.Lock()
+	defer e.Unlock()
+	value := e.setSequence(responses, func(v interface{}) (interface{}, bool) {
+		items, ok := v.(map[string]*memcache.Item)
+		return items, ok
+	})
+	e.items, _ = value.(map[string]*memcache.Item)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedGetMulti) clone() Expectation {
+	c := &ExpectedGetMulti{keysBasedExpectation: e.keysBasedExpectation, items: e.items, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedGetMulti) String() string {
+	msg := "ExpectedGetMulti => expecting call to GetMulti():\n"
+	msg += fmt.Sprintf("\t- is with keys: %s\n", e.keysMatcher())
+	if e.items != nil {
+		msg += fmt.Sprintf("\t- returns items: %v\n", e.items)
+	}
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedIncrement is used to manage *memcache.Client.Increment expectations
+type ExpectedIncrement struct {
+	commonExpectation
+	keyBasedExpectation
+	deltaBasedExpectation
+	value   uint64
+	respond func(key string, delta uint64) (uint64, error)
+}
+
+// WithKeyAndDelta will match given expected key and delta value to actual key and delta used when calling memcache.Client.Increment().
+// If at least one parameter does not match, it will return an error.
+// Both key and delta may be given as a Matcher, e.g. KeyPrefix or DeltaBetween.
+func (e *ExpectedIncrement) WithKeyAndDelta(key interface{}, delta interface{}) *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKey = toKeyMatcher(key)
+	e.expectedDelta = toDeltaMatcher(delta)
+	return e
+}
+
+// WillReturnValue specifies the value that will be returned when calling memcache.Client.Increment().
+func (e *ExpectedIncrement) WillReturnValue(value uint64) *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	e.value = value
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Increment() from the actual key and delta.
+// The callback result replaces any value or error set with the WillReturn* methods.
+func (e *ExpectedIncrement) WillRespond(fn func(key string, delta uint64) (uint64, error)) *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Increment() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedIncrement) OnServer(addr string) *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Increment().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedIncrement) Then() *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(e.value)
+	e.value = 0
+	return e
+}
+
+// WillReturnSequence sets the responses of successive calls to memcache.Client.Increment().
+// Each response is a non-negative integer, an error, or nil for zero without error.
+// Unless the number of calls is set, one call per response is awaited. The last response is returned by any further call.
+// It panics on a response of another type.
+func (e *ExpectedIncrement) WillReturnSequence(responses ...interface{}) *ExpectedIncrement {
+	e.Lock()
+	defer e.Unlock()
+	value := e.setSequence(responses, func(v interface{}) (interface{}, bool) {
+		return toUint64(v)
+	})
+	e.value, _ = value.(uint64)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedIncrement) clone() Expectation {
+	c := &ExpectedIncrement{keyBasedExpectation: e.keyBasedExpectation, deltaBasedExpectation: e.deltaBasedExpectation, value: e.value, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedIncrement) String() string {
+	msg := "ExpectedIncrement => expecting call to Increment():\n"
+	msg += fmt.Sprintf("\t- is with key: %s\n", e.keyMatcher())
+	msg += fmt.Sprintf("\t- and with delta: %s\n", e.deltaMatcher())
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedPing is used to manage *memcache.Client.Ping expectations
+type ExpectedPing struct {
+	commonExpectation
+	respond func() error
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Ping().
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedPing) WillRespond(fn func() error) *ExpectedPing {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Ping().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedPing) Then() *ExpectedPing {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Ping(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedPing) WillReturnSequence(errs ...error) *ExpectedPing {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedPing) clone() Expectation {
+	c := &ExpectedPing{respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
+// String returns string representation
+func (e *ExpectedPing) String() string {
 	msg := "ExpectedPing => expecting call to Ping()\n"
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
 	return msg + e.commonExpectation.String()
 }
 
@@ -437,24 +1755,168 @@ func (e *ExpectedPing) String() string {
 type ExpectedPrepend struct {
 	commonExpectation
 	itemBasedExpectation
+	respond func(item *memcache.Item) error
 }
 
 // WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Prepend().
 // If at least one field does not match, it will return an error.
-func (e *ExpectedPrepend) WithItem(item *memcache.Item) *ExpectedPrepend {
-	e.expectedItem = item
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedPrepend) WithItem(item interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
+	return e
+}
+
+// WithItemKey will match only the key of the item used when calling memcache.Client.Prepend().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedPrepend) WithItemKey(key interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
+}
+
+// WithItemValue will match only the value of the item used when calling memcache.Client.Prepend().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedPrepend) WithItemValue(value interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
+}
+
+// WithJSONValue will match only the value of the item used when calling memcache.Client.Prepend(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedPrepend) WithJSONValue(v interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
+	return e
+}
+
+// WithGobValue will match only the value of the item used when calling memcache.Client.Prepend(),
+// if it holds the gob encoding of v.
+func (e *ExpectedPrepend) WithGobValue(v interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
+	return e
+}
+
+// WithCodecValue will match only the value of the item used when calling memcache.Client.Prepend(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedPrepend) WithCodecValue(codec Codec, v interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
+	return e
+}
+
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.Prepend().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedPrepend) WithItemFlags(flags interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
+}
+
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.Prepend().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedPrepend) WithItemExpiration(expiration interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
+}
+
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedPrepend) WithItemExpirationBetween(min, max int32) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
+	return e
+}
+
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.Prepend().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedPrepend) WithItemCasID(casID interface{}) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
+	return e
+}
+
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedPrepend) IgnoringExpiration() *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
+}
+
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedPrepend) IgnoringCasID() *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Prepend() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedPrepend) WillRespond(fn func(item *memcache.Item) error) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Prepend() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedPrepend) OnServer(addr string) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Prepend().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedPrepend) Then() *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Prepend(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedPrepend) WillReturnSequence(errs ...error) *ExpectedPrepend {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
 	return e
 }
 
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedPrepend) clone() Expectation {
+	c := &ExpectedPrepend{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
 // String returns string representation
 func (e *ExpectedPrepend) String() string {
 	msg := "ExpectedPrepend => expecting call to Prepend():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
 	}
 	return msg + e.commonExpectation.String()
 }
@@ -463,24 +1925,168 @@ func (e *ExpectedPrepend) String() string {
 type ExpectedReplace struct {
 	commonExpectation
 	itemBasedExpectation
+	respond func(item *memcache.Item) error
 }
 
 // WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Replace().
 // If at least one field does not match, it will return an error.
-func (e *ExpectedReplace) WithItem(item *memcache.Item) *ExpectedReplace {
-	e.expectedItem = item
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedReplace) WithItem(item interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
+	return e
+}
+
+// WithItemKey will match only the key of the item used when calling memcache.Client.Replace().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedReplace) WithItemKey(key interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
+}
+
+// WithItemValue will match only the value of the item used when calling memcache.Client.Replace().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedReplace) WithItemValue(value interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
+}
+
+// WithJSONValue will match only the value of the item used when calling memcache.Client.Replace(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedReplace) WithJSONValue(v interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
+	return e
+}
+
+// WithGobValue will match only the value of the item used when calling memcache.Client.Replace(),
+// if it holds the gob encoding of v.
+func (e *ExpectedReplace) WithGobValue(v interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
+	return e
+}
+
+// WithCodecValue will match only the value of the item used when calling memcache.Client.Replace(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedReplace) WithCodecValue(codec Codec, v interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
+	return e
+}
+
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.Replace().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedReplace) WithItemFlags(flags interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
+}
+
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.Replace().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedReplace) WithItemExpiration(expiration interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
+}
+
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedReplace) WithItemExpirationBetween(min, max int32) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
+	return e
+}
+
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.Replace().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedReplace) WithItemCasID(casID interface{}) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
+	return e
+}
+
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedReplace) IgnoringExpiration() *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
+}
+
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedReplace) IgnoringCasID() *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Replace() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedReplace) WillRespond(fn func(item *memcache.Item) error) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Replace() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedReplace) OnServer(addr string) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Replace().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedReplace) Then() *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Replace(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedReplace) WillReturnSequence(errs ...error) *ExpectedReplace {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
 	return e
 }
 
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedReplace) clone() Expectation {
+	c := &ExpectedReplace{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
 // String returns string representation
 func (e *ExpectedReplace) String() string {
 	msg := "ExpectedReplace => expecting call to Replace():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
 	}
 	return msg + e.commonExpectation.String()
 }
@@ -489,24 +2095,168 @@ func (e *ExpectedReplace) String() string {
 type ExpectedSet struct {
 	commonExpectation
 	itemBasedExpectation
+	respond func(item *memcache.Item) error
 }
 
 // WithItem will match given expected memcache.Item to actual memcache.Item used when calling memcache.Client.Set().
 // If at least one field does not match, it will return an error.
-func (e *ExpectedSet) WithItem(item *memcache.Item) *ExpectedSet {
-	e.expectedItem = item
+// The item may also be given as a Matcher, e.g. MatchItem().Key("some-key").
+func (e *ExpectedSet) WithItem(item interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.setItem(item)
 	return e
 }
 
+// WithItemKey will match only the key of the item used when calling memcache.Client.Set().
+// It can be combined with the other WithItem* methods. The key may be given as a Matcher.
+func (e *ExpectedSet) WithItemKey(key interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Key(key)
+	return e
+}
+
+// WithItemValue will match only the value of the item used when calling memcache.Client.Set().
+// It can be combined with the other WithItem* methods. The value may be given as a Matcher.
+func (e *ExpectedSet) WithItemValue(value interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(value)
+	return e
+}
+
+// WithJSONValue will match only the value of the item used when calling memcache.Client.Set(),
+// if it holds the JSON encoding of v. The order of the fields and whitespace do not matter.
+func (e *ExpectedSet) WithJSONValue(v interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(JSONValue(v))
+	return e
+}
+
+// WithGobValue will match only the value of the item used when calling memcache.Client.Set(),
+// if it holds the gob encoding of v.
+func (e *ExpectedSet) WithGobValue(v interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(GobValue(v))
+	return e
+}
+
+// WithCodecValue will match only the value of the item used when calling memcache.Client.Set(),
+// if it decodes with codec to a value equal to v.
+func (e *ExpectedSet) WithCodecValue(codec Codec, v interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Value(CodecValue(codec, v))
+	return e
+}
+
+// WithItemFlags will match only the flags of the item used when calling memcache.Client.Set().
+// It can be combined with the other WithItem* methods. The flags may be given as a Matcher.
+func (e *ExpectedSet) WithItemFlags(flags interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Flags(flags)
+	return e
+}
+
+// WithItemExpiration will match only the expiration of the item used when calling memcache.Client.Set().
+// It can be combined with the other WithItem* methods. The expiration may be given as a Matcher.
+func (e *ExpectedSet) WithItemExpiration(expiration interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().Expiration(expiration)
+	return e
+}
+
+// WithItemExpirationBetween will match an item expiration in the closed interval [min, max].
+func (e *ExpectedSet) WithItemExpirationBetween(min, max int32) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().ExpirationBetween(min, max)
+	return e
+}
+
+// WithItemCasID will match only the casID of the item used when calling memcache.Client.Set().
+// It can be combined with the other WithItem* methods. The casID may be given as a Matcher.
+func (e *ExpectedSet) WithItemCasID(casID interface{}) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().CasID(casID)
+	return e
+}
+
+// IgnoringExpiration stops comparing the expiration of the item set with WithItem.
+func (e *ExpectedSet) IgnoringExpiration() *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringExpiration()
+	return e
+}
+
+// IgnoringCasID stops comparing the casID of the item set with WithItem.
+func (e *ExpectedSet) IgnoringCasID() *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.itemFields().IgnoringCasID()
+	return e
+}
+
+// WillRespond specifies a callback computing the response of memcache.Client.Set() from the actual item.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedSet) WillRespond(fn func(item *memcache.Item) error) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Set() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedSet) OnServer(addr string) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Set().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedSet) Then() *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Set(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedSet) WillReturnSequence(errs ...error) *ExpectedSet {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedSet) clone() Expectation {
+	c := &ExpectedSet{itemBasedExpectation: e.itemBasedExpectation.clone(), respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
 // String returns string representation
 func (e *ExpectedSet) String() string {
 	msg := "ExpectedSet => expecting call to Set():\n"
-	if e.expectedItem != nil {
-		msg += fmt.Sprintf("\t- is with item with key: %s\n", e.expectedItem.Key)
-		msg += fmt.Sprintf("\t- and with value: %s\n", string(e.expectedItem.Value))
-		msg += fmt.Sprintf("\t- and with flags: %d\n", e.expectedItem.Flags)
-		msg += fmt.Sprintf("\t- and expiration date: %d\n", e.expectedItem.Expiration)
-		msg += fmt.Sprintf("\t- and with casID: %d\n", e.expectedItem.CasID)
+	msg += e.itemBasedExpectation.String()
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
 	}
 	return msg + e.commonExpectation.String()
 }
@@ -516,20 +2266,74 @@ type ExpectedTouch struct {
 	commonExpectation
 	keyBasedExpectation
 	secondsBasedExpectation
+	respond func(key string, seconds int32) error
 }
 
 // WithKeyAndSeconds will match given expected key and seconds value to actual key and seconds used when calling memcache.Client.Touch().
 // If at least one parameter does not match, it will return an error.
-func (e *ExpectedTouch) WithKeyAndSeconds(key string, seconds int32) *ExpectedTouch {
-	e.expectedKey = key
-	e.expectedSeconds = seconds
+// Both key and seconds may be given as a Matcher, e.g. KeyPrefix or SecondsBetween.
+func (e *ExpectedTouch) WithKeyAndSeconds(key interface{}, seconds interface{}) *ExpectedTouch {
+	e.Lock()
+	defer e.Unlock()
+	e.expectedKey = toKeyMatcher(key)
+	e.expectedSeconds = toSecondsMatcher(seconds)
 	return e
 }
 
+// WillRespond specifies a callback computing the response of memcache.Client.Touch() from the actual key and seconds.
+// The callback result replaces any error set with WillReturnError.
+func (e *ExpectedTouch) WillRespond(fn func(key string, seconds int32) error) *ExpectedTouch {
+	e.Lock()
+	defer e.Unlock()
+	e.respond = fn
+	return e
+}
+
+// OnServer will match only calls to memcache.Client.Touch() whose key is routed to the server addr,
+// among the servers given to New or NewFromSelector.
+func (e *ExpectedTouch) OnServer(addr string) *ExpectedTouch {
+	e.Lock()
+	defer e.Unlock()
+	e.server = normalizeAddr(addr)
+	return e
+}
+
+// Then queues the response set so far, returned by the next call to memcache.Client.Touch().
+// The following WillReturn* calls set the response of the call after it.
+func (e *ExpectedTouch) Then() *ExpectedTouch {
+	e.Lock()
+	defer e.Unlock()
+	e.queueResponse(nil)
+	return e
+}
+
+// WillReturnSequence sets the errors returned by successive calls to memcache.Client.Touch(), nil meaning success.
+// Unless the number of calls is set, one call per error is awaited. The last error is returned by any further call.
+func (e *ExpectedTouch) WillReturnSequence(errs ...error) *ExpectedTouch {
+	e.Lock()
+	defer e.Unlock()
+	responses := make([]interface{}, len(errs))
+	for i, err := range errs {
+		responses[i] = err
+	}
+	e.setSequence(responses, nil)
+	return e
+}
+
+// clone returns a copy of the expectation, without its prerequisites and group
+func (e *ExpectedTouch) clone() Expectation {
+	c := &ExpectedTouch{keyBasedExpectation: e.keyBasedExpectation, secondsBasedExpectation: e.secondsBasedExpectation, respond: e.respond}
+	e.commonExpectation.copyTo(&c.commonExpectation)
+	return c
+}
+
 // String returns string representation
 func (e *ExpectedTouch) String() string {
 	msg := "ExpectedTouch => expecting call to Touch():\n"
-	msg += fmt.Sprintf("\t- is with key: %s\n", e.expectedKey)
-	msg += fmt.Sprintf("\t- and with seconds: %d\n", e.expectedSeconds)
+	msg += fmt.Sprintf("\t- is with key: %s\n", e.keyMatcher())
+	msg += fmt.Sprintf("\t- and with seconds: %s\n", e.secondsMatcher())
+	if e.respond != nil {
+		msg += "\t- responds with a callback\n"
+	}
 	return msg + e.commonExpectation.String()
 }
@@ -1,8 +1,10 @@
 package memcachemock
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +37,278 @@ func TestMaybe(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 }
 
+func TestAtLeast(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectGet().WithKey("job").AtLeast(2)
+	mock.ExpectDelete().WithKey("job")
+	_, err := mock.Get("job")
+	a.NoError(err)
+	a.Error(mock.Delete("job"), "the polling loop is not satisfied yet")
+	_, err = mock.Get("job")
+	a.NoError(err)
+	_, err = mock.Get("job")
+	a.NoError(err, "the expectation is satisfied but not saturated")
+	a.NoError(mock.Delete("job"), "a satisfied expectation lets the next one match")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAtMost(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectPing().AtMost(2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(mock.Ping())
+	a.NoError(mock.Ping())
+	a.Error(mock.Ping())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestBetween(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectPing().Between(2, 3)
+	a.NoError(mock.Ping())
+	a.Error(mock.ExpectationsWereMet())
+	a.NoError(mock.Ping())
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(mock.Ping())
+	a.Error(mock.Ping())
+	a.NoError(mock.ExpectationsWereMet())
+
+	a.Panics(func() { mock.ExpectPing().Between(3, 2) })
+}
+
+func TestAnyTimes(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectPing().AnyTimes()
+	mock.ExpectClose()
+	a.Error(mock.ExpectationsWereMet(), "the close expectation is not met")
+	for i := 0; i < 10; i++ {
+		a.NoError(mock.Ping())
+	}
+	a.NoError(mock.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNever(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectGet().WithKey("foo")
+	mock.ExpectDelete().WithKey("foo").Never()
+	a.Error(mock.ExpectationsWereMet(), "the get expectation is not met")
+	_, err := mock.Get("foo")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+
+	err = mock.Delete("foo")
+	if a.Error(err) {
+		a.Contains(err.Error(), "call to method Delete() was expected to never happen")
+	}
+	err = mock.ExpectationsWereMet()
+	if a.Error(err) {
+		a.Contains(err.Error(), "ExpectedDelete")
+		a.Contains(err.Error(), "called 1 time, but must never be called")
+	}
+}
+
+func TestNever_OtherArguments(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectDelete().WithKey("foo").Never()
+	mock.ExpectDelete().WithKey("bar")
+	a.NoError(mock.Delete("bar"))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNever_CatchAll(t *testing.T) {
+	for _, neverFirst := range []bool{false, true} {
+		mock := New("localhost:11211")
+		a := assert.New(t)
+
+		if neverFirst {
+			mock.ExpectGet().WithKey(AnyKey()).Never()
+		}
+		mock.ExpectGet().WithKey("a")
+		if !neverFirst {
+			mock.ExpectGet().WithKey(AnyKey()).Never()
+		}
+		_, err := mock.Get("a")
+		a.NoError(err, "the specific expectation should be matched")
+		a.NoError(mock.ExpectationsWereMet())
+
+		_, err = mock.Get("a")
+		if a.Error(err) {
+			a.Contains(err.Error(), "call to method Get() was expected to never happen")
+		}
+		_, err = mock.Get("b")
+		a.Error(err)
+		err = mock.ExpectationsWereMet()
+		if a.Error(err) {
+			a.Contains(err.Error(), "there is an expectation which was called too many times")
+			a.Contains(err.Error(), "called 2 times, but must never be called")
+		}
+	}
+}
+
+func TestWillDelayFor(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectGet().WithKey("foo").WillDelayFor(50 * time.Millisecond).WillReturnError(memcache.ErrCacheMiss)
+	start := time.Now()
+	_, err := mock.Get("foo")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillDelayBetween(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectPing().WillDelayBetween(10*time.Millisecond, 30*time.Millisecond).Times(5)
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		a.NoError(mock.Ping())
+		a.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+	a.Panics(func() { mock.ExpectPing().WillDelayBetween(time.Second, time.Millisecond) })
+}
+
+func TestWillBlockUntil(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	unblock := make(chan struct{})
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectGet().WithKey("slow").WillBlockUntil(unblock)
+	mock.ExpectGet().WithKey("fast")
+
+	done := make(chan error)
+	go func() {
+		_, err := mock.Get("slow")
+		done <- err
+	}()
+	select {
+	case <-done:
+		a.Fail("the call should block")
+	case <-time.After(20 * time.Millisecond):
+	}
+	_, err := mock.Get("fast")
+	a.NoError(err, "a blocked call does not block the other calls")
+
+	close(unblock)
+	a.NoError(<-done)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillBlockUntil_Timeout(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	mock.ExpectGet().WithKey("foo").WillBlockUntil(unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		_, err := mock.Get("foo")
+		result <- err
+	}()
+	select {
+	case <-result:
+		a.Fail("the call should block")
+	case <-ctx.Done():
+		a.ErrorIs(ctx.Err(), context.DeadlineExceeded)
+	}
+}
+
+func TestWillPanic(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectGet().WithKey("foo").WillPanic("cache exploded")
+	a.Contains(mock.expectations[0].String(), "panics with: cache exploded")
+	a.PanicsWithValue("cache exploded", func() { _, _ = mock.Get("foo") })
+	a.NoError(mock.ExpectationsWereMet(), "the call which panicked counts as matched")
+	calls := mock.CallsTo("Get")
+	if a.Len(calls, 1) {
+		a.True(calls[0].Panicked)
+		a.Equal("cache exploded", calls[0].PanicValue)
+		a.Equal(`#0 Get("foo") => panic: cache exploded`, calls[0].String())
+	}
+}
+
+func TestWillPanic_Recovered(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	recovering := func(fn func() error) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("recovered: %v", r)
+			}
+		}()
+		return fn()
+	}
+	mock.ExpectSet().WithItemKey("foo").WillPanic(memcache.ErrServerError).Times(2)
+	for i := 0; i < 2; i++ {
+		err := recovering(func() error { return mock.Set(&memcache.Item{Key: "foo"}) })
+		a.EqualError(err, "recovered: memcache: server error")
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDo(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	var seen []CallArgs
+	record := func(args CallArgs) { seen = append(seen, args) }
+	mock.ExpectIncrement().WithKeyAndDelta("counter", AnyDelta()).Do(record).Do(record).WillReturnError(memcache.ErrCacheMiss)
+	mock.ExpectSet().WithItemKey("foo").Do(record)
+	mock.ExpectGetMulti().WithKeys([]string{"a", "b"}).Do(record)
+	mock.ExpectTouch().WithKeyAndSeconds("foo", 10).Do(record)
+	a.Contains(mock.expectations[0].String(), "runs 2 side effects")
+
+	_, err := mock.Increment("counter", 3)
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.NoError(mock.Set(&memcache.Item{Key: "foo", Value: []byte("bar")}))
+	_, err = mock.GetMulti([]string{"a", "b"})
+	a.NoError(err)
+	a.NoError(mock.Touch("foo", 10))
+
+	a.Equal([]CallArgs{
+		{Method: "Increment", Key: "counter", Delta: 3},
+		{Method: "Increment", Key: "counter", Delta: 3},
+		{Method: "Set", Item: &memcache.Item{Key: "foo", Value: []byte("bar")}},
+		{Method: "GetMulti", Keys: []string{"a", "b"}},
+		{Method: "Touch", Key: "foo", Seconds: 10},
+	}, seen)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDo_ThenPanic(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	var called bool
+	mock.ExpectPing().Do(func(args CallArgs) { called = true }).WillPanic("boom")
+	a.Panics(func() { _ = mock.Ping() })
+	a.True(called, "the side effect runs before the panic")
+}
+
 func TestWillReturnError(t *testing.T) {
 	mock := New("localhost:11211")
 	a := assert.New(t)
@@ -235,6 +509,127 @@ func TestItemMatches_DifferentCasID(t *testing.T) {
 	a.Error(mock.ExpectationsWereMet())
 }
 
+func TestItemMatches_Matcher(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectSet().
+		WithItem(MatchItem().Key(KeyPrefix("user:")))
+	err := mock.Set(&memcache.Item{Key: "session:1"})
+	a.ErrorContains(err, `expected key with prefix "user:", but got key session:1`)
+	err = mock.Set(&memcache.Item{Key: "user:1", Value: []byte("some value")})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_CustomMatcher(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectAdd().
+		WithItem(Any())
+	mock.ExpectAdd().
+		WithItem(Eq("not an item"))
+	a.NoError(mock.Add(&memcache.Item{Key: "some-key"}))
+	err := mock.Add(&memcache.Item{Key: "some-key"})
+	a.ErrorContains(err, "expected not an item, but got item with key some-key")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_NilItemWithFields(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectReplace().
+		WithItemKey("some-key")
+	err := mock.Replace(nil)
+	a.ErrorContains(err, "expected item with key: some-key, but got no item")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_SelectedFields(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectSet().
+		WithItemKey("some-key").
+		WithItemValue([]byte("some value")).
+		WithItemFlags(uint32(1)).
+		WithItemExpirationBetween(60, 120)
+	err := mock.Set(&memcache.Item{
+		Key:        "some-key",
+		Value:      []byte("some value"),
+		Flags:      1,
+		Expiration: 90,
+		CasID:      123,
+	})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_IgnoringCasIDAndExpiration(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectCompareAndSwap().
+		WithItem(&memcache.Item{Key: "some-key", Value: []byte("v"), CasID: 1, Expiration: 10}).
+		IgnoringCasID().
+		IgnoringExpiration()
+	err := mock.CompareAndSwap(&memcache.Item{Key: "some-key", Value: []byte("v"), CasID: 42, Expiration: 20})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_AllDifferingFieldsReported(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectPrepend().
+		WithItemKey("some-key").
+		WithItemValue("some value").
+		WithItemFlags(1).
+		WithItemExpiration(10).
+		WithItemCasID(5)
+	err := mock.Prepend(&memcache.Item{Key: "some-key", Value: []byte("other value"), Flags: 2, Expiration: 10, CasID: 6})
+	a.Error(err)
+	a.Equal("item with key some-key does not match:\n"+
+		"\t- expected value some value, but got value other value\n"+
+		"\t- expected flags 1, but got flags 2\n"+
+		"\t- expected casID 5, but got casID 6", err.Error())
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestItemMatches_SharedMatcher(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	base := MatchItem().Key("some-key")
+	mock.ExpectSet().WithItem(base).WithItemValue("x")
+	mock.ExpectAdd().WithItem(base)
+	a.NoError(mock.Set(&memcache.Item{Key: "some-key", Value: []byte("x")}))
+	a.NoError(mock.Add(&memcache.Item{Key: "some-key", Value: []byte("y")}))
+	a.NoError(mock.ExpectationsWereMet())
+	a.True(base.Match(&memcache.Item{Key: "some-key", Value: []byte("y")}), "the matcher given to WithItem is left unchanged")
+}
+
+func TestItemMatches_FieldsAfterOtherMatcher(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	a.PanicsWithValue("memcachemock: item fields cannot be set on an item expected with any value, use an ItemMatcher instead", func() {
+		mock.ExpectSet().WithItem(Any()).WithItemKey("some-key")
+	})
+	a.Panics(func() {
+		mock.ExpectCompareAndSwap().WithItem(Eq(&memcache.Item{Key: "some-key"})).IgnoringCasID()
+	})
+}
+
+func TestItemExpectationString(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectAppend().
+		WithItemValue("suffix")
+	mock.ExpectAppend().
+		WithItem(Any())
+	a.Equal("ExpectedAppend => expecting call to Append():\n"+
+		"\t- is with item with any key\n"+
+		"\t- and with value: suffix\n", mock.expectations[0].String())
+	a.Equal("ExpectedAppend => expecting call to Append():\n"+
+		"\t- is with any value\n", mock.expectations[1].String())
+}
+
 func TestDeltaMatches(t *testing.T) {
 	mock := New("localhost:11211")
 	a := assert.New(t)
@@ -291,7 +686,177 @@ func TestExpectationStrings(t *testing.T) {
 		"another-key": {},
 	})
 	for _, ex := range mock.expectations {
-		fmt.Sprintln(ex)
+		a.NotEmpty(ex.String())
 	}
 	a.Error(mock.ExpectationsWereMet())
 }
+
+func TestWillReturnSequence(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	item := &memcache.Item{Key: "k", Value: []byte("v")}
+	mock.ExpectGet().WithKey("k").WillReturnSequence(memcache.ErrCacheMiss, item, item)
+	_, err := mock.Get("k")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.Error(mock.ExpectationsWereMet(), "one call per response is awaited")
+	for i := 0; i < 2; i++ {
+		it, err := mock.Get("k")
+		a.NoError(err)
+		a.Equal(item, it)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+	_, err = mock.Get("k")
+	a.Error(err)
+}
+
+func TestWillReturnSequence_LastResponseRepeats(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectIncrement().WithKeyAndDelta("counter", 1).WillReturnSequence(1, uint64(2), memcache.ErrServerError).AnyTimes()
+	for _, expected := range []uint64{1, 2} {
+		value, err := mock.Increment("counter", 1)
+		a.NoError(err)
+		a.Equal(expected, value)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := mock.Increment("counter", 1)
+		a.ErrorIs(err, memcache.ErrServerError)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnSequence_Errors(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectSet().WithItemKey("k").WillReturnSequence(memcache.ErrServerError, nil)
+	a.ErrorIs(mock.Set(&memcache.Item{Key: "k"}), memcache.ErrServerError)
+	a.NoError(mock.Set(&memcache.Item{Key: "k"}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnSequence_Panics(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	a.Panics(func() { mock.ExpectGet().WillReturnSequence("not an item") })
+	a.Panics(func() { mock.ExpectDecrement().WillReturnSequence(-1) })
+}
+
+func TestThen(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	items := map[string]*memcache.Item{"a": {Key: "a"}}
+	ex := mock.ExpectGetMulti().WithKeys([]string{"a"}).WillReturnItems(items).Then()
+	ex.WillReturnError(memcache.ErrServerError)
+	a.Contains(ex.String(), "responds with a sequence of 2 responses")
+
+	got, err := mock.GetMulti([]string{"a"})
+	a.NoError(err)
+	a.Equal(items, got)
+	got, err = mock.GetMulti([]string{"a"})
+	a.ErrorIs(err, memcache.ErrServerError)
+	a.Nil(got)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestThen_Times(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+
+	mock.ExpectPing().Then().Times(3).WillReturnError(memcache.ErrNoServers)
+	a.NoError(mock.Ping())
+	a.ErrorIs(mock.Ping(), memcache.ErrNoServers)
+	a.ErrorIs(mock.Ping(), memcache.ErrNoServers)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillRespond(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	var stored *memcache.Item
+	mock.ExpectSet().
+		WithItemKey(KeyPrefix("user:")).
+		WillRespond(func(item *memcache.Item) error {
+			stored = item
+			return nil
+		})
+	mock.ExpectGet().
+		WithKey(AnyKey()).
+		WillReturnItem(&memcache.Item{Key: "static"}).
+		WillRespond(func(key string) (*memcache.Item, error) {
+			return stored, nil
+		})
+	item := &memcache.Item{Key: "user:1", Value: []byte("some value")}
+	a.NoError(mock.Set(item))
+	result, err := mock.Get("user:1")
+	a.NoError(err)
+	a.Equal(item, result)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillRespond_AllMethods(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	itemErr := func(item *memcache.Item) error { return fmt.Errorf("%s failed", item.Key) }
+	noArgsErr := func() error { return memcache.ErrServerError }
+	mock.ExpectAdd().WithItem(MatchItem()).WillRespond(itemErr)
+	mock.ExpectAppend().WithItem(MatchItem()).WillRespond(itemErr)
+	mock.ExpectCompareAndSwap().WithItem(MatchItem()).WillRespond(itemErr)
+	mock.ExpectPrepend().WithItem(MatchItem()).WillRespond(itemErr)
+	mock.ExpectReplace().WithItem(MatchItem()).WillRespond(itemErr)
+	mock.ExpectClose().WillRespond(noArgsErr)
+	mock.ExpectDeleteAll().WillRespond(noArgsErr)
+	mock.ExpectFlushAll().WillRespond(noArgsErr)
+	mock.ExpectPing().WillRespond(noArgsErr)
+	mock.ExpectIncrement().
+		WithKeyAndDelta("counter", AnyDelta()).
+		WillRespond(func(key string, delta uint64) (uint64, error) { return 100 + delta, nil })
+	mock.ExpectDecrement().
+		WithKeyAndDelta("counter", AnyDelta()).
+		WillRespond(func(key string, delta uint64) (uint64, error) { return 100 - delta, nil })
+	mock.ExpectDelete().
+		WithKey(AnyKey()).
+		WillRespond(func(key string) error { return fmt.Errorf("%s not deleted", key) })
+	mock.ExpectGetMulti().
+		WithKeys(AnyKeys()).
+		WillRespond(func(keys []string) (map[string]*memcache.Item, error) {
+			items := map[string]*memcache.Item{}
+			for _, key := range keys {
+				items[key] = &memcache.Item{Key: key}
+			}
+			return items, nil
+		})
+	mock.ExpectTouch().
+		WithKeyAndSeconds(AnyKey(), AnySeconds()).
+		WillRespond(func(key string, seconds int32) error { return fmt.Errorf("%s touched for %d", key, seconds) })
+
+	a.EqualError(mock.Add(&memcache.Item{Key: "add"}), "add failed")
+	a.EqualError(mock.Append(&memcache.Item{Key: "append"}), "append failed")
+	a.EqualError(mock.CompareAndSwap(&memcache.Item{Key: "cas"}), "cas failed")
+	a.EqualError(mock.Prepend(&memcache.Item{Key: "prepend"}), "prepend failed")
+	a.EqualError(mock.Replace(&memcache.Item{Key: "replace"}), "replace failed")
+	a.ErrorIs(mock.Close(), memcache.ErrServerError)
+	a.ErrorIs(mock.DeleteAll(), memcache.ErrServerError)
+	a.ErrorIs(mock.FlushAll(), memcache.ErrServerError)
+	a.ErrorIs(mock.Ping(), memcache.ErrServerError)
+	value, err := mock.Increment("counter", 5)
+	a.NoError(err)
+	a.Equal(uint64(105), value)
+	value, err = mock.Decrement("counter", 5)
+	a.NoError(err)
+	a.Equal(uint64(95), value)
+	a.EqualError(mock.Delete("some-key"), "some-key not deleted")
+	items, err := mock.GetMulti([]string{"a", "b"})
+	a.NoError(err)
+	a.Len(items, 2)
+	a.EqualError(mock.Touch("some-key", 10), "some-key touched for 10")
+	a.NoError(mock.ExpectationsWereMet())
+
+	for _, ex := range mock.expectations {
+		a.Contains(ex.String(), "responds with a callback")
+	}
+}
@@ -0,0 +1,323 @@
+package memcachemock
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// maxRelativeExpiration is the longest expiration memcached takes as relative to now, 30 days.
+// Longer expirations are absolute Unix timestamps.
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
+// errNonNumericValue is the error returned by memcached when incrementing or decrementing a non-numeric value
+var errNonNumericValue = errors.New("memcache: client error: cannot increment or decrement non-numeric value")
+
+// Fake is an in-memory memcache client following memcached semantics.
+// Unlike the mock, it does not need expectations: it stores items,
+// and returns the same errors a *memcache.Client would get from a real server.
+// Keys are validated as a *memcache.Client does, an invalid key failing with memcache.ErrMalformedKey.
+// A Fake is safe for concurrent use.
+type Fake struct {
+	mu    sync.Mutex
+	clock Clock
+	items map[string]*fakeEntry
+	casID uint64 // last compare and swap ID given to an item
+}
+
+// fakeEntry is an item stored in the Fake
+type fakeEntry struct {
+	item      *memcache.Item
+	expiresAt time.Time // zero value means that the item never expires
+}
+
+var _ Client = (*Fake)(nil)
+
+// NewFake returns an empty in-memory memcache client, expiring items with the system time.
+func NewFake() *Fake {
+	return NewFakeWithClock(systemClock{})
+}
+
+// NewFakeWithClock returns an empty in-memory memcache client, expiring items with the given clock.
+// Use a ManualClock to expire items without sleeping.
+func NewFakeWithClock(clock Clock) *Fake {
+	return &Fake{clock: clock, items: map[string]*fakeEntry{}}
+}
+
+// Add writes the given item, if no value already exists for its key.
+// ErrNotStored is returned if that condition is not met.
+func (f *Fake) Add(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.load(item.Key); ok {
+		return memcache.ErrNotStored
+	}
+	f.store(item)
+	return nil
+}
+
+// Append appends the given item to the existing item, if a value already exists for its key.
+// ErrNotStored is returned if that condition is not met.
+func (f *Fake) Append(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(item.Key)
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	f.storeValue(existing, append(existing.item.Value, item.Value...))
+	return nil
+}
+
+// Close does nothing, as there are no connections to close.
+func (f *Fake) Close() error {
+	return nil
+}
+
+// CompareAndSwap writes the given item that was previously returned by Get,
+// if the value was neither modified or evicted between the Get and the CompareAndSwap calls.
+// ErrCASConflict is returned if the value was modified in between the calls,
+// and ErrCacheMiss if there is no value for the item key.
+func (f *Fake) CompareAndSwap(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(item.Key)
+	if !ok {
+		return memcache.ErrCacheMiss
+	}
+	if existing.item.CasID != item.CasID {
+		return memcache.ErrCASConflict
+	}
+	f.store(item)
+	return nil
+}
+
+// Decrement atomically decrements key by delta.
+// On underflow, the new value is capped at zero.
+func (f *Fake) Decrement(key string, delta uint64) (newValue uint64, err error) {
+	return f.incrDecr(key, func(value uint64) uint64 {
+		if delta > value {
+			return 0
+		}
+		return value - delta
+	})
+}
+
+// Delete deletes the item with the provided key.
+// ErrCacheMiss is returned if the item didn't already exist in the cache.
+func (f *Fake) Delete(key string) error {
+	if !legalKey(key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.load(key); !ok {
+		return memcache.ErrCacheMiss
+	}
+	delete(f.items, key)
+	return nil
+}
+
+// DeleteAll deletes all items in the cache.
+func (f *Fake) DeleteAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = map[string]*fakeEntry{}
+	return nil
+}
+
+// FlushAll deletes all items in the cache.
+func (f *Fake) FlushAll() error {
+	return f.DeleteAll()
+}
+
+// Get gets the item for the given key.
+// ErrCacheMiss is returned for a cache miss.
+func (f *Fake) Get(key string) (item *memcache.Item, err error) {
+	if !legalKey(key) {
+		return nil, memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(key)
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	return copyItem(existing.item), nil
+}
+
+// GetMulti is a batch version of Get. The returned map contains only the keys found in the cache.
+func (f *Fake) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	for _, key := range keys {
+		if !legalKey(key) {
+			return nil, memcache.ErrMalformedKey
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := make(map[string]*memcache.Item)
+	for _, key := range keys {
+		if existing, ok := f.load(key); ok {
+			items[key] = copyItem(existing.item)
+		}
+	}
+	return items, nil
+}
+
+// Increment atomically increments key by delta.
+// On 64-bit overflow, the new value wraps around.
+func (f *Fake) Increment(key string, delta uint64) (newValue uint64, err error) {
+	return f.incrDecr(key, func(value uint64) uint64 {
+		return value + delta
+	})
+}
+
+// Ping always succeeds, as the in-memory store is always available.
+func (f *Fake) Ping() error {
+	return nil
+}
+
+// Prepend prepends the given item to the existing item, if a value already exists for its key.
+// ErrNotStored is returned if that condition is not met.
+func (f *Fake) Prepend(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(item.Key)
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	value := make([]byte, 0, len(item.Value)+len(existing.item.Value))
+	f.storeValue(existing, append(append(value, item.Value...), existing.item.Value...))
+	return nil
+}
+
+// Replace writes the given item, but only if the server already holds data for this key.
+// ErrNotStored is returned if that condition is not met.
+func (f *Fake) Replace(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.load(item.Key); !ok {
+		return memcache.ErrNotStored
+	}
+	f.store(item)
+	return nil
+}
+
+// Set writes the given item, unconditionally.
+func (f *Fake) Set(item *memcache.Item) error {
+	if !legalKey(item.Key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(item)
+	return nil
+}
+
+// Touch updates the expiry for the given key.
+// ErrCacheMiss is returned if the item is not in the cache.
+func (f *Fake) Touch(key string, seconds int32) (err error) {
+	if !legalKey(key) {
+		return memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(key)
+	if !ok {
+		return memcache.ErrCacheMiss
+	}
+	existing.item.Expiration = seconds
+	existing.expiresAt = f.expiresAt(seconds)
+	return nil
+}
+
+// incrDecr applies op to the decimal value stored at key, and stores the result.
+func (f *Fake) incrDecr(key string, op func(uint64) uint64) (uint64, error) {
+	if !legalKey(key) {
+		return 0, memcache.ErrMalformedKey
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.load(key)
+	if !ok {
+		return 0, memcache.ErrCacheMiss
+	}
+	value, err := strconv.ParseUint(string(existing.item.Value), 10, 64)
+	if err != nil {
+		return 0, errNonNumericValue
+	}
+	value = op(value)
+	f.storeValue(existing, []byte(strconv.FormatUint(value, 10)))
+	return value, nil
+}
+
+// load returns the stored entry for key, if any, removing it when expired. The caller must hold f.mu.
+func (f *Fake) load(key string) (*fakeEntry, bool) {
+	entry, ok := f.items[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expiresAt.IsZero() && !f.clock.Now().Before(entry.expiresAt) {
+		delete(f.items, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// store saves a copy of item with a new compare and swap ID. The caller must hold f.mu.
+func (f *Fake) store(item *memcache.Item) {
+	stored := copyItem(item)
+	f.casID++
+	stored.CasID = f.casID
+	f.items[item.Key] = &fakeEntry{item: stored, expiresAt: f.expiresAt(item.Expiration)}
+}
+
+// storeValue updates the value of a stored entry, keeping its flags and expiration. The caller must hold f.mu.
+func (f *Fake) storeValue(entry *fakeEntry, value []byte) {
+	f.casID++
+	entry.item.Value = value
+	entry.item.CasID = f.casID
+}
+
+// expiresAt returns the expiration time of an item following memcached rules:
+// zero means no expiration, a negative value expires immediately,
+// up to 30 days it is relative to now, and beyond it is an absolute Unix timestamp.
+func (f *Fake) expiresAt(expiration int32) time.Time {
+	now := f.clock.Now()
+	switch {
+	case expiration == 0:
+		return time.Time{}
+	case expiration < 0:
+		return now
+	case expiration <= maxRelativeExpiration:
+		return now.Add(time.Duration(expiration) * time.Second)
+	}
+	return time.Unix(int64(expiration), 0)
+}
+
+func copyItem(item *memcache.Item) *memcache.Item {
+	c := *item
+	if item.Value != nil {
+		c.Value = make([]byte, len(item.Value))
+		copy(c.Value, item.Value)
+	}
+	return &c
+}
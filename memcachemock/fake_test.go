@@ -0,0 +1,292 @@
+package memcachemock
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake_SetAndGet(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	item := &memcache.Item{Key: "some-key", Value: []byte("some value"), Flags: 3}
+	a.NoError(fake.Set(item))
+	result, err := fake.Get("some-key")
+	a.NoError(err)
+	a.Equal("some-key", result.Key)
+	a.Equal([]byte("some value"), result.Value)
+	a.Equal(uint32(3), result.Flags)
+	a.NotZero(result.CasID)
+
+	result.Value[0] = 'S'
+	item.Value[0] = 'X'
+	again, err := fake.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("some value"), again.Value)
+}
+
+func TestFake_GetMiss(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	item, err := fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.Nil(item)
+}
+
+func TestFake_Add(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.NoError(fake.Add(&memcache.Item{Key: "some-key", Value: []byte("first")}))
+	a.ErrorIs(fake.Add(&memcache.Item{Key: "some-key", Value: []byte("second")}), memcache.ErrNotStored)
+	item, err := fake.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("first"), item.Value)
+}
+
+func TestFake_Replace(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.ErrorIs(fake.Replace(&memcache.Item{Key: "some-key"}), memcache.ErrNotStored)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Value: []byte("first")}))
+	a.NoError(fake.Replace(&memcache.Item{Key: "some-key", Value: []byte("second")}))
+	item, err := fake.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("second"), item.Value)
+}
+
+func TestFake_AppendAndPrepend(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.ErrorIs(fake.Append(&memcache.Item{Key: "some-key", Value: []byte("c")}), memcache.ErrNotStored)
+	a.ErrorIs(fake.Prepend(&memcache.Item{Key: "some-key", Value: []byte("a")}), memcache.ErrNotStored)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Value: []byte("b"), Flags: 7}))
+	a.NoError(fake.Append(&memcache.Item{Key: "some-key", Value: []byte("c"), Flags: 1}))
+	a.NoError(fake.Prepend(&memcache.Item{Key: "some-key", Value: []byte("a"), Flags: 1}))
+	item, err := fake.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("abc"), item.Value)
+	a.Equal(uint32(7), item.Flags)
+}
+
+func TestFake_CompareAndSwap(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.ErrorIs(fake.CompareAndSwap(&memcache.Item{Key: "some-key"}), memcache.ErrCacheMiss)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Value: []byte("first")}))
+	item, err := fake.Get("some-key")
+	a.NoError(err)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Value: []byte("concurrent")}))
+	item.Value = []byte("second")
+	a.ErrorIs(fake.CompareAndSwap(item), memcache.ErrCASConflict)
+
+	item, err = fake.Get("some-key")
+	a.NoError(err)
+	item.Value = []byte("second")
+	a.NoError(fake.CompareAndSwap(item))
+	item, err = fake.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("second"), item.Value)
+}
+
+func TestFake_IncrementAndDecrement(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	_, err := fake.Increment("counter", 1)
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	_, err = fake.Decrement("counter", 1)
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+
+	a.NoError(fake.Set(&memcache.Item{Key: "counter", Value: []byte("10")}))
+	value, err := fake.Increment("counter", 5)
+	a.NoError(err)
+	a.Equal(uint64(15), value)
+	value, err = fake.Decrement("counter", 20)
+	a.NoError(err)
+	a.Equal(uint64(0), value)
+
+	a.NoError(fake.Set(&memcache.Item{Key: "counter", Value: []byte("18446744073709551615")}))
+	value, err = fake.Increment("counter", 2)
+	a.NoError(err)
+	a.Equal(uint64(1), value)
+	item, err := fake.Get("counter")
+	a.NoError(err)
+	a.Equal([]byte("1"), item.Value)
+
+	a.NoError(fake.Set(&memcache.Item{Key: "text", Value: []byte("abc")}))
+	_, err = fake.Increment("text", 1)
+	a.EqualError(err, "memcache: client error: cannot increment or decrement non-numeric value")
+}
+
+func TestFake_Delete(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.ErrorIs(fake.Delete("some-key"), memcache.ErrCacheMiss)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key"}))
+	a.NoError(fake.Delete("some-key"))
+	_, err := fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFake_DeleteAllAndFlushAll(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "a"}))
+	a.NoError(fake.DeleteAll())
+	_, err := fake.Get("a")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.NoError(fake.Set(&memcache.Item{Key: "b"}))
+	a.NoError(fake.FlushAll())
+	_, err = fake.Get("b")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFake_GetMulti(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "a", Value: []byte("1")}))
+	a.NoError(fake.Set(&memcache.Item{Key: "c", Value: []byte("3")}))
+	items, err := fake.GetMulti([]string{"a", "b", "c"})
+	a.NoError(err)
+	a.Len(items, 2)
+	a.Equal([]byte("1"), items["a"].Value)
+	a.Equal([]byte("3"), items["c"].Value)
+}
+
+func TestFake_Touch(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.ErrorIs(fake.Touch("some-key", 10), memcache.ErrCacheMiss)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key"}))
+	a.NoError(fake.Touch("some-key", 10))
+}
+
+func TestFake_PingAndClose(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.NoError(fake.Ping())
+	a.NoError(fake.Close())
+}
+
+func TestFake_MalformedKey(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	long := strings.Repeat("k", maxKeyLength+1)
+	for _, key := range []string{"bad key", "bad\nkey", "bad\x7fkey", long} {
+		item := &memcache.Item{Key: key, Value: []byte("1")}
+		a.ErrorIs(fake.Set(item), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Add(item), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Replace(item), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Append(item), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Prepend(item), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.CompareAndSwap(item), memcache.ErrMalformedKey, key)
+		_, err := fake.Get(key)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		_, err = fake.GetMulti([]string{"good-key", key})
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		_, err = fake.Increment(key, 1)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		_, err = fake.Decrement(key, 1)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Touch(key, 10), memcache.ErrMalformedKey, key)
+		a.ErrorIs(fake.Delete(key), memcache.ErrMalformedKey, key)
+	}
+	a.Empty(fake.items)
+}
+
+func TestFake_Concurrent(t *testing.T) {
+	fake := NewFake()
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "counter", Value: []byte("0")}))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fake.Increment("counter", 1)
+			a.NoError(err)
+		}()
+	}
+	wg.Wait()
+	item, err := fake.Get("counter")
+	a.NoError(err)
+	a.Equal([]byte("50"), item.Value)
+}
+
+func TestFake_RelativeExpiration(t *testing.T) {
+	clock := NewManualClock(time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC))
+	fake := NewFakeWithClock(clock)
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Expiration: 60}))
+	clock.Advance(59 * time.Second)
+	_, err := fake.Get("some-key")
+	a.NoError(err)
+	clock.Advance(time.Second)
+	_, err = fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.NoError(fake.Add(&memcache.Item{Key: "some-key"}))
+}
+
+func TestFake_AbsoluteExpiration(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(now)
+	fake := NewFakeWithClock(clock)
+	a := assert.New(t)
+	expiration := int32(now.Add(40 * 24 * time.Hour).Unix())
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Expiration: expiration}))
+	clock.Advance(39 * 24 * time.Hour)
+	_, err := fake.Get("some-key")
+	a.NoError(err)
+	clock.Advance(24 * time.Hour)
+	_, err = fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+
+	a.NoError(fake.Set(&memcache.Item{Key: "past-key", Expiration: int32(now.Add(-time.Hour).Unix())}))
+	_, err = fake.Get("past-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFake_ThirtyDaysIsRelative(t *testing.T) {
+	clock := NewManualClock(time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC))
+	fake := NewFakeWithClock(clock)
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Expiration: maxRelativeExpiration}))
+	clock.Advance(30*24*time.Hour - time.Second)
+	_, err := fake.Get("some-key")
+	a.NoError(err)
+	clock.Advance(time.Second)
+	_, err = fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFake_NegativeExpiration(t *testing.T) {
+	fake := NewFakeWithClock(NewManualClock(time.Now()))
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Expiration: -1}))
+	_, err := fake.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFake_TouchExtendsExpiration(t *testing.T) {
+	clock := NewManualClock(time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC))
+	fake := NewFakeWithClock(clock)
+	a := assert.New(t)
+	a.NoError(fake.Set(&memcache.Item{Key: "some-key", Value: []byte("1"), Expiration: 10}))
+	clock.Advance(5 * time.Second)
+	a.NoError(fake.Touch("some-key", 60))
+	clock.Advance(30 * time.Second)
+	_, err := fake.Increment("some-key", 1)
+	a.NoError(err)
+	a.NoError(fake.Append(&memcache.Item{Key: "some-key", Value: []byte("0")}))
+	clock.Advance(30 * time.Second)
+	a.ErrorIs(fake.Touch("some-key", 60), memcache.ErrCacheMiss)
+
+	a.NoError(fake.Set(&memcache.Item{Key: "forever", Expiration: 10}))
+	a.NoError(fake.Touch("forever", 0))
+	clock.Advance(365 * 24 * time.Hour)
+	_, err = fake.Get("forever")
+	a.NoError(err)
+}
@@ -0,0 +1,344 @@
+package memcachemock
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"gopkg.in/yaml.v3"
+)
+
+// fixture is the schema of the files read by LoadExpectations
+type fixture struct {
+	Ordered      *bool                `yaml:"ordered"`
+	Expectations []fixtureExpectation `yaml:"expectations"`
+}
+
+// fixtureExpectation is an expectation read by LoadExpectations
+type fixtureExpectation struct {
+	Method   string        `yaml:"method"`
+	Key      *string       `yaml:"key"`
+	Keys     []string      `yaml:"keys"`
+	Item     *fixtureItem  `yaml:"item"`
+	Delta    *uint64       `yaml:"delta"`
+	Seconds  *int32        `yaml:"seconds"`
+	Return   fixtureReturn `yaml:"return"`
+	Error    string        `yaml:"error"`
+	Times    uint          `yaml:"times"`
+	Optional bool          `yaml:"optional"`
+}
+
+// fixtureItem is an item, either matched or returned. Only the fields set are matched.
+type fixtureItem struct {
+	Key        *string `yaml:"key"`
+	Value      *string `yaml:"value"`
+	Flags      *uint32 `yaml:"flags"`
+	Expiration *int32  `yaml:"expiration"`
+	CasID      *uint64 `yaml:"cas_id"`
+}
+
+// fixtureReturn holds the values returned by an expectation
+type fixtureReturn struct {
+	Item  *fixtureItem           `yaml:"item"`
+	Items map[string]fixtureItem `yaml:"items"`
+	Value *uint64                `yaml:"value"`
+}
+
+// fixtureErrors are the errors which can be returned by name
+var fixtureErrors = map[string]error{
+	"ErrCacheMiss":    memcache.ErrCacheMiss,
+	"ErrCASConflict":  memcache.ErrCASConflict,
+	"ErrNotStored":    memcache.ErrNotStored,
+	"ErrServerError":  memcache.ErrServerError,
+	"ErrNoStats":      memcache.ErrNoStats,
+	"ErrMalformedKey": memcache.ErrMalformedKey,
+	"ErrNoServers":    memcache.ErrNoServers,
+}
+
+// LoadExpectations returns a mock without servers, expecting the calls described by the YAML or JSON document r.
+// See (*Mock).LoadExpectations for the schema.
+func LoadExpectations(r io.Reader) (*Mock, error) {
+	mock := New()
+	if err := mock.LoadExpectations(r); err != nil {
+		return nil, err
+	}
+	return mock, nil
+}
+
+// FromFile returns a mock without servers, expecting the calls described by the YAML or JSON file at path.
+// See (*Mock).LoadExpectations for the schema.
+func FromFile(path string) (*Mock, error) {
+	mock := New()
+	if err := mock.LoadFile(path); err != nil {
+		return nil, err
+	}
+	return mock, nil
+}
+
+// LoadFile adds the expectations described by the YAML or JSON file at path.
+// See LoadExpectations for the schema.
+func (c *Mock) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.LoadExpectations(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadExpectations adds the expectations described by the YAML or JSON document r, in order:
+//
+//	ordered: false             # optional, as MatchExpectationsInOrder
+//	expectations:
+//	  - method: Get            # name of the *memcache.Client method
+//	    key: foo               # Get, Delete, Touch, Increment and Decrement
+//	    return:
+//	      item: {key: foo, value: bar, flags: 1, expiration: 60, cas_id: 2}
+//	  - method: GetMulti
+//	    keys: [foo, bar]
+//	    return:
+//	      items: {foo: {value: bar}}
+//	  - method: Set            # Add, Append, CompareAndSwap, Prepend, Replace and Set
+//	    item: {key: foo, value: bar}   # only the fields set are matched
+//	    error: ErrNotStored    # name of a memcache.Err* error
+//	  - method: Increment      # and Decrement
+//	    key: counter
+//	    delta: 1
+//	    return: {value: 2}
+//	  - method: Touch
+//	    key: foo
+//	    seconds: 60
+//	  - method: Ping           # Close, DeleteAll, FlushAll and Ping
+//	    times: 2
+//	    optional: true
+//
+// Unknown fields, and fields which do not apply to the method, are reported as errors.
+// Nothing is added to the mock if the document is invalid.
+func (c *Mock) LoadExpectations(r io.Reader) error {
+	var f fixture
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && err != io.EOF {
+		return fmt.Errorf("memcachemock: cannot decode expectations: %w", err)
+	}
+	builders := make([]func(*Mock) Expectation, len(f.Expectations))
+	for i, fe := range f.Expectations {
+		build, err := fe.builder()
+		if err != nil {
+			return fmt.Errorf("memcachemock: expectation #%d: %w", i+1, err)
+		}
+		builders[i] = build
+	}
+	if f.Ordered != nil {
+		c.MatchExpectationsInOrder(*f.Ordered)
+	}
+	for i, build := range builders {
+		fe := f.Expectations[i]
+		modifier := build(c).(CallModifier)
+		if fe.Times > 0 {
+			modifier.Times(fe.Times)
+		}
+		if fe.Optional {
+			modifier.Maybe()
+		}
+		if fe.Error != "" {
+			modifier.WillReturnError(fixtureErrors[fe.Error])
+		}
+	}
+	return nil
+}
+
+// builder validates the expectation, and returns the function adding it to a mock.
+func (fe fixtureExpectation) builder() (func(*Mock) Expectation, error) {
+	var fields []string
+	if fe.Key != nil {
+		fields = append(fields, "key")
+	}
+	if fe.Keys != nil {
+		fields = append(fields, "keys")
+	}
+	if fe.Item != nil {
+		fields = append(fields, "item")
+	}
+	if fe.Delta != nil {
+		fields = append(fields, "delta")
+	}
+	if fe.Seconds != nil {
+		fields = append(fields, "seconds")
+	}
+	if fe.Return.Item != nil {
+		fields = append(fields, "return.item")
+	}
+	if fe.Return.Items != nil {
+		fields = append(fields, "return.items")
+	}
+	if fe.Return.Value != nil {
+		fields = append(fields, "return.value")
+	}
+	if _, ok := fixtureErrors[fe.Error]; fe.Error != "" && !ok {
+		return nil, fmt.Errorf("unknown error %q, expected one of %s", fe.Error, fixtureErrorNames())
+	}
+
+	var required, optional []string
+	var build func(*Mock) Expectation
+	switch fe.Method {
+	case "Add", "Append", "CompareAndSwap", "Prepend", "Replace", "Set":
+		required = []string{"item"}
+		build = func(c *Mock) Expectation {
+			var e interface {
+				Expectation
+				itemFields() *ItemMatcher
+			}
+			switch fe.Method {
+			case "Add":
+				e = c.ExpectAdd()
+			case "Append":
+				e = c.ExpectAppend()
+			case "CompareAndSwap":
+				e = c.ExpectCompareAndSwap()
+			case "Prepend":
+				e = c.ExpectPrepend()
+			case "Replace":
+				e = c.ExpectReplace()
+			default:
+				e = c.ExpectSet()
+			}
+			e.Lock()
+			defer e.Unlock()
+			fe.Item.match(e.itemFields())
+			return e
+		}
+	case "Get":
+		required, optional = []string{"key"}, []string{"return.item"}
+		build = func(c *Mock) Expectation {
+			e := c.ExpectGet().WithKey(*fe.Key)
+			if fe.Return.Item != nil {
+				e.WillReturnItem(fe.Return.Item.item(*fe.Key))
+			}
+			return e
+		}
+	case "GetMulti":
+		required, optional = []string{"keys"}, []string{"return.items"}
+		build = func(c *Mock) Expectation {
+			e := c.ExpectGetMulti().WithKeys(fe.Keys)
+			if fe.Return.Items != nil {
+				items := make(map[string]*memcache.Item, len(fe.Return.Items))
+				for key, item := range fe.Return.Items {
+					items[key] = item.item(key)
+				}
+				e.WillReturnItems(items)
+			}
+			return e
+		}
+	case "Increment", "Decrement":
+		required, optional = []string{"key", "delta"}, []string{"return.value"}
+		build = func(c *Mock) Expectation {
+			var value uint64
+			if fe.Return.Value != nil {
+				value = *fe.Return.Value
+			}
+			if fe.Method == "Increment" {
+				return c.ExpectIncrement().WithKeyAndDelta(*fe.Key, *fe.Delta).WillReturnValue(value)
+			}
+			return c.ExpectDecrement().WithKeyAndDelta(*fe.Key, *fe.Delta).WillReturnValue(value)
+		}
+	case "Delete":
+		required = []string{"key"}
+		build = func(c *Mock) Expectation {
+			return c.ExpectDelete().WithKey(*fe.Key)
+		}
+	case "Touch":
+		required = []string{"key", "seconds"}
+		build = func(c *Mock) Expectation {
+			return c.ExpectTouch().WithKeyAndSeconds(*fe.Key, *fe.Seconds)
+		}
+	case "Close":
+		build = func(c *Mock) Expectation { return c.ExpectClose() }
+	case "DeleteAll":
+		build = func(c *Mock) Expectation { return c.ExpectDeleteAll() }
+	case "FlushAll":
+		build = func(c *Mock) Expectation { return c.ExpectFlushAll() }
+	case "Ping":
+		build = func(c *Mock) Expectation { return c.ExpectPing() }
+	case "":
+		return nil, fmt.Errorf("missing method")
+	default:
+		return nil, fmt.Errorf("unknown method %q", fe.Method)
+	}
+
+	for _, field := range required {
+		if !contains(fields, field) {
+			return nil, fmt.Errorf("%s requires %s", fe.Method, field)
+		}
+	}
+	for _, field := range fields {
+		if !contains(required, field) && !contains(optional, field) {
+			return nil, fmt.Errorf("%s does not use %s", fe.Method, field)
+		}
+	}
+	return build, nil
+}
+
+// match sets the fields of the item on m
+func (fi *fixtureItem) match(m *ItemMatcher) {
+	if fi.Key != nil {
+		m.Key(*fi.Key)
+	}
+	if fi.Value != nil {
+		m.Value(*fi.Value)
+	}
+	if fi.Flags != nil {
+		m.Flags(*fi.Flags)
+	}
+	if fi.Expiration != nil {
+		m.Expiration(*fi.Expiration)
+	}
+	if fi.CasID != nil {
+		m.CasID(*fi.CasID)
+	}
+}
+
+// item returns the item described, with the given key unless it is set.
+func (fi *fixtureItem) item(key string) *memcache.Item {
+	item := &memcache.Item{Key: key}
+	if fi.Key != nil {
+		item.Key = *fi.Key
+	}
+	if fi.Value != nil {
+		item.Value = []byte(*fi.Value)
+	}
+	if fi.Flags != nil {
+		item.Flags = *fi.Flags
+	}
+	if fi.Expiration != nil {
+		item.Expiration = *fi.Expiration
+	}
+	if fi.CasID != nil {
+		item.CasID = *fi.CasID
+	}
+	return item
+}
+
+func fixtureErrorNames() string {
+	names := make([]string, 0, len(fixtureErrors))
+	for name := range fixtureErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
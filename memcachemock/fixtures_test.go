@@ -0,0 +1,163 @@
+package memcachemock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const everyMethod = `
+ordered: true
+expectations:
+  - method: Add
+    item: {key: a, value: "1"}
+    error: ErrNotStored
+  - method: Append
+    item: {key: a}
+  - method: Close
+  - method: CompareAndSwap
+    item: {key: a, cas_id: 7}
+    error: ErrCASConflict
+  - method: Decrement
+    key: counter
+    delta: 2
+    return: {value: 8}
+  - method: Delete
+    key: a
+  - method: DeleteAll
+  - method: FlushAll
+  - method: Get
+    key: a
+    return:
+      item: {value: "1", flags: 3}
+  - method: GetMulti
+    keys: [a, b]
+    return:
+      items:
+        a: {value: "1"}
+  - method: Increment
+    key: counter
+    delta: 1
+    return: {value: 9}
+  - method: Ping
+    times: 2
+  - method: Prepend
+    item: {key: a, flags: 1, expiration: 60}
+  - method: Replace
+    item: {}
+  - method: Set
+    item: {key: a}
+  - method: Touch
+    key: a
+    seconds: 10
+  - method: Ping
+    optional: true
+`
+
+func TestLoadExpectations_EveryMethod(t *testing.T) {
+	a := assert.New(t)
+	mock, err := LoadExpectations(strings.NewReader(everyMethod))
+	require.NoError(t, err)
+	require.Len(t, mock.expectations, 17)
+
+	a.ErrorIs(mock.Add(&memcache.Item{Key: "a", Value: []byte("1")}), memcache.ErrNotStored)
+	a.NoError(mock.Append(&memcache.Item{Key: "a", Value: []byte("anything")}))
+	a.NoError(mock.Close())
+	a.ErrorIs(mock.CompareAndSwap(&memcache.Item{Key: "a", CasID: 7}), memcache.ErrCASConflict)
+	value, err := mock.Decrement("counter", 2)
+	a.NoError(err)
+	a.Equal(uint64(8), value)
+	a.NoError(mock.Delete("a"))
+	a.NoError(mock.DeleteAll())
+	a.NoError(mock.FlushAll())
+	item, err := mock.Get("a")
+	a.NoError(err)
+	a.Equal(&memcache.Item{Key: "a", Value: []byte("1"), Flags: 3}, item)
+	items, err := mock.GetMulti([]string{"a", "b"})
+	a.NoError(err)
+	a.Equal(map[string]*memcache.Item{"a": {Key: "a", Value: []byte("1")}}, items)
+	value, err = mock.Increment("counter", 1)
+	a.NoError(err)
+	a.Equal(uint64(9), value)
+	a.NoError(mock.Ping())
+	a.NoError(mock.Ping())
+	a.NoError(mock.Prepend(&memcache.Item{Key: "a", Flags: 1, Expiration: 60}))
+	a.NoError(mock.Replace(&memcache.Item{Key: "b"}))
+	a.NoError(mock.Set(&memcache.Item{Key: "a", Value: []byte("2")}))
+	a.NoError(mock.Touch("a", 10))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestLoadExpectations_JSON(t *testing.T) {
+	a := assert.New(t)
+	mock, err := LoadExpectations(strings.NewReader(`{
+		"ordered": false,
+		"expectations": [
+			{"method": "Delete", "key": "b", "error": "ErrCacheMiss"},
+			{"method": "Get", "key": "a", "return": {"item": {"value": "x"}}}
+		]
+	}`))
+	require.NoError(t, err)
+
+	item, err := mock.Get("a")
+	a.NoError(err)
+	a.Equal([]byte("x"), item.Value)
+	a.ErrorIs(mock.Delete("b"), memcache.ErrCacheMiss)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestFromFile(t *testing.T) {
+	a := assert.New(t)
+	mock, err := FromFile("testdata/scenario.yaml")
+	require.NoError(t, err)
+
+	_, err = mock.Get("user:1")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.NoError(mock.Set(&memcache.Item{Key: "user:1", Value: []byte(`{"name":"gopher"}`), Expiration: 300}))
+	item, err := mock.Get("user:1")
+	a.NoError(err)
+	a.Equal("user:1", item.Key)
+	a.NoError(mock.ExpectationsWereMet())
+
+	_, err = FromFile("testdata/missing.yaml")
+	a.Error(err)
+}
+
+func TestLoadExpectations_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		doc string
+		err string
+	}{
+		"syntax":        {"expectations: [", "cannot decode expectations"},
+		"unknown field": {"expectations: [{method: Get, key: a, typo: 1}]", "field typo not found"},
+		"no method":     {"expectations: [{key: a}]", "expectation #1: missing method"},
+		"bad method":    {"expectations: [{method: Stats}]", `expectation #1: unknown method "Stats"`},
+		"missing key":   {"expectations: [{method: Ping}, {method: Get}]", "expectation #2: Get requires key"},
+		"missing delta": {"expectations: [{method: Increment, key: a}]", "Increment requires delta"},
+		"missing item":  {"expectations: [{method: Set}]", "Set requires item"},
+		"unused field":  {"expectations: [{method: Get, key: a, delta: 1}]", "Get does not use delta"},
+		"unused return": {"expectations: [{method: Set, item: {}, return: {value: 1}}]", "Set does not use return.value"},
+		"bad error":     {"expectations: [{method: Ping, error: ErrTimeout}]", `unknown error "ErrTimeout", expected one of ErrCASConflict, ErrCacheMiss`},
+		"bad delta":     {"expectations: [{method: Increment, key: a, delta: -1}]", "cannot decode expectations"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			mock := New()
+			err := mock.LoadExpectations(strings.NewReader(test.doc))
+			if a.Error(err) {
+				a.Contains(err.Error(), test.err)
+			}
+			a.Empty(mock.expectations, "nothing is added from an invalid document")
+		})
+	}
+}
+
+func TestLoadExpectations_Empty(t *testing.T) {
+	mock, err := LoadExpectations(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
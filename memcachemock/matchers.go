@@ -0,0 +1,443 @@
+package memcachemock
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Matcher is used to match the actual arguments of a mocked method call.
+// Every With* builder accepts either a literal value or a Matcher.
+type Matcher interface {
+	// Match reports whether the actual argument satisfies the matcher.
+	Match(arg interface{}) bool
+	// String returns a readable description of the matcher,
+	// used in expectation strings and mismatch errors.
+	String() string
+}
+
+// matcherFunc is a Matcher built from a description and a match function
+type matcherFunc struct {
+	desc string
+	fn   func(arg interface{}) bool
+}
+
+func (m *matcherFunc) Match(arg interface{}) bool {
+	return m.fn(arg)
+}
+
+func (m *matcherFunc) String() string {
+	return m.desc
+}
+
+// Any matches any argument.
+func Any() Matcher {
+	return &matcherFunc{desc: "any value", fn: func(interface{}) bool { return true }}
+}
+
+// Eq matches an argument deeply equal to v.
+func Eq(v interface{}) Matcher {
+	return &matcherFunc{desc: fmt.Sprintf("%v", v), fn: func(arg interface{}) bool {
+		return reflect.DeepEqual(v, arg)
+	}}
+}
+
+// Keys Matchers
+
+// AnyKey matches any key.
+func AnyKey() Matcher {
+	return stringMatcher("any key", func(string) bool { return true })
+}
+
+// KeyPrefix matches keys starting with prefix.
+func KeyPrefix(prefix string) Matcher {
+	return stringMatcher(fmt.Sprintf("with prefix %q", prefix), func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// KeySuffix matches keys ending with suffix.
+func KeySuffix(suffix string) Matcher {
+	return stringMatcher(fmt.Sprintf("with suffix %q", suffix), func(key string) bool {
+		return strings.HasSuffix(key, suffix)
+	})
+}
+
+// KeyContains matches keys containing substr.
+func KeyContains(substr string) Matcher {
+	return stringMatcher(fmt.Sprintf("containing %q", substr), func(key string) bool {
+		return strings.Contains(key, substr)
+	})
+}
+
+// KeyRegexp matches keys matching the regular expression expr.
+// It panics if expr cannot be compiled.
+func KeyRegexp(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+	return stringMatcher(fmt.Sprintf("matching /%s/", expr), re.MatchString)
+}
+
+// KeyFunc matches keys for which fn returns true.
+// The description is used in expectation strings and mismatch errors.
+func KeyFunc(description string, fn func(key string) bool) Matcher {
+	return stringMatcher(description, fn)
+}
+
+// AnyKeys matches any slice of keys.
+func AnyKeys() Matcher {
+	return stringsMatcher("any keys", func([]string) bool { return true })
+}
+
+// KeysInAnyOrder matches a slice holding exactly the given keys, in any order.
+func KeysInAnyOrder(keys ...string) Matcher {
+	expected := sortedCopy(keys)
+	return stringsMatcher(fmt.Sprintf("%v in any order", keys), func(actual []string) bool {
+		return reflect.DeepEqual(expected, sortedCopy(actual))
+	})
+}
+
+// KeysContaining matches a slice holding at least the given keys.
+func KeysContaining(keys ...string) Matcher {
+	return stringsMatcher(fmt.Sprintf("containing %v", keys), func(actual []string) bool {
+		present := make(map[string]bool, len(actual))
+		for _, key := range actual {
+			present[key] = true
+		}
+		for _, key := range keys {
+			if !present[key] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// KeysFunc matches slices of keys for which fn returns true.
+// The description is used in expectation strings and mismatch errors.
+func KeysFunc(description string, fn func(keys []string) bool) Matcher {
+	return stringsMatcher(description, fn)
+}
+
+// Deltas Matchers
+
+// AnyDelta matches any delta.
+func AnyDelta() Matcher {
+	return uint64Matcher("any delta", func(uint64) bool { return true })
+}
+
+// DeltaBetween matches deltas in the closed interval [min, max].
+func DeltaBetween(min, max uint64) Matcher {
+	return uint64Matcher(fmt.Sprintf("between %d and %d", min, max), func(delta uint64) bool {
+		return delta >= min && delta <= max
+	})
+}
+
+// DeltaFunc matches deltas for which fn returns true.
+// The description is used in expectation strings and mismatch errors.
+func DeltaFunc(description string, fn func(delta uint64) bool) Matcher {
+	return uint64Matcher(description, fn)
+}
+
+// Seconds Matchers
+
+// AnySeconds matches any number of seconds.
+func AnySeconds() Matcher {
+	return int32Matcher("any seconds", func(int32) bool { return true })
+}
+
+// SecondsBetween matches seconds in the closed interval [min, max].
+func SecondsBetween(min, max int32) Matcher {
+	return int32Matcher(fmt.Sprintf("between %d and %d", min, max), func(seconds int32) bool {
+		return seconds >= min && seconds <= max
+	})
+}
+
+// SecondsFunc matches seconds for which fn returns true.
+// The description is used in expectation strings and mismatch errors.
+func SecondsFunc(description string, fn func(seconds int32) bool) Matcher {
+	return int32Matcher(description, fn)
+}
+
+func stringMatcher(desc string, fn func(string) bool) Matcher {
+	return &matcherFunc{desc: desc, fn: func(arg interface{}) bool {
+		s, ok := arg.(string)
+		return ok && fn(s)
+	}}
+}
+
+func stringsMatcher(desc string, fn func([]string) bool) Matcher {
+	return &matcherFunc{desc: desc, fn: func(arg interface{}) bool {
+		s, ok := arg.([]string)
+		return ok && fn(s)
+	}}
+}
+
+func uint64Matcher(desc string, fn func(uint64) bool) Matcher {
+	return &matcherFunc{desc: desc, fn: func(arg interface{}) bool {
+		n, ok := arg.(uint64)
+		return ok && fn(n)
+	}}
+}
+
+func int32Matcher(desc string, fn func(int32) bool) Matcher {
+	return &matcherFunc{desc: desc, fn: func(arg interface{}) bool {
+		n, ok := arg.(int32)
+		return ok && fn(n)
+	}}
+}
+
+func sortedCopy(keys []string) []string {
+	sorted := make([]string, len(keys))
+	copy(sorted, keys)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// toKeyMatcher converts the argument of a With* builder to a key Matcher.
+func toKeyMatcher(key interface{}) Matcher {
+	switch k := key.(type) {
+	case Matcher:
+		return k
+	case string:
+		return Eq(k)
+	}
+	panic(fmt.Sprintf("memcachemock: key must be a string or a Matcher, got %T", key))
+}
+
+// toKeysMatcher converts the argument of a With* builder to a keys Matcher.
+func toKeysMatcher(keys interface{}) Matcher {
+	switch k := keys.(type) {
+	case Matcher:
+		return k
+	case []string:
+		return Eq(k)
+	case nil:
+		return Eq([]string(nil))
+	}
+	panic(fmt.Sprintf("memcachemock: keys must be a []string or a Matcher, got %T", keys))
+}
+
+// toDeltaMatcher converts the argument of a With* builder to a delta Matcher.
+// Any integer value is accepted, so untyped constants can be used.
+func toDeltaMatcher(delta interface{}) Matcher {
+	return toUint64Matcher("delta", delta)
+}
+
+// toUint64Matcher converts the argument of a With* builder to an uint64 Matcher.
+func toUint64Matcher(name string, n interface{}) Matcher {
+	if m, ok := n.(Matcher); ok {
+		return m
+	}
+	if u, ok := toUint64(n); ok {
+		return Eq(u)
+	}
+	panic(fmt.Sprintf("memcachemock: %s must be a non-negative integer or a Matcher, got %T(%v)", name, n, n))
+}
+
+// toUint64 converts any non-negative integer to an uint64.
+func toUint64(n interface{}) (uint64, bool) {
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= 0 {
+			return uint64(v.Int()), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	}
+	return 0, false
+}
+
+// toSecondsMatcher converts the argument of a With* builder to a seconds Matcher.
+// Any integer value fitting in an int32 is accepted, so untyped constants can be used.
+func toSecondsMatcher(seconds interface{}) Matcher {
+	if m, ok := seconds.(Matcher); ok {
+		return m
+	}
+	v := reflect.ValueOf(seconds)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); int64(int32(n)) == n {
+			return Eq(int32(n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := v.Uint(); n <= 1<<31-1 {
+			return Eq(int32(n))
+		}
+	}
+	panic(fmt.Sprintf("memcachemock: seconds must be an int32 or a Matcher, got %T(%v)", seconds, seconds))
+}
+
+// Items Matchers
+
+// ItemMatcher matches a memcache.Item field by field.
+// Only the fields that were set on the matcher are compared.
+type ItemMatcher struct {
+	key        Matcher
+	value      Matcher
+	flags      Matcher
+	expiration Matcher
+	casID      Matcher
+}
+
+// MatchItem returns an ItemMatcher that matches any non-nil memcache.Item
+// until fields are set on it.
+func MatchItem() *ItemMatcher {
+	return &ItemMatcher{}
+}
+
+// matchItemFields returns an ItemMatcher comparing every field of item.
+func matchItemFields(item *memcache.Item) *ItemMatcher {
+	return MatchItem().
+		Key(item.Key).
+		Value(item.Value).
+		Flags(item.Flags).
+		Expiration(item.Expiration).
+		CasID(item.CasID)
+}
+
+// Key sets the expected item key. It accepts a string or a Matcher.
+func (m *ItemMatcher) Key(key interface{}) *ItemMatcher {
+	m.key = toKeyMatcher(key)
+	return m
+}
+
+// Value sets the expected item value. It accepts a []byte, a string or a Matcher.
+func (m *ItemMatcher) Value(value interface{}) *ItemMatcher {
+	m.value = toValueMatcher(value)
+	return m
+}
+
+// Flags sets the expected item flags. It accepts an integer or a Matcher.
+func (m *ItemMatcher) Flags(flags interface{}) *ItemMatcher {
+	m.flags = toUint32Matcher("flags", flags)
+	return m
+}
+
+// Expiration sets the expected item expiration. It accepts an int32 or a Matcher.
+func (m *ItemMatcher) Expiration(expiration interface{}) *ItemMatcher {
+	m.expiration = toSecondsMatcher(expiration)
+	return m
+}
+
+// ExpirationBetween expects an item expiration in the closed interval [min, max].
+func (m *ItemMatcher) ExpirationBetween(min, max int32) *ItemMatcher {
+	m.expiration = SecondsBetween(min, max)
+	return m
+}
+
+// CasID sets the expected item casID. It accepts an integer or a Matcher.
+func (m *ItemMatcher) CasID(casID interface{}) *ItemMatcher {
+	m.casID = toUint64Matcher("casID", casID)
+	return m
+}
+
+// IgnoringFlags stops comparing the item flags.
+func (m *ItemMatcher) IgnoringFlags() *ItemMatcher {
+	m.flags = nil
+	return m
+}
+
+// IgnoringExpiration stops comparing the item expiration.
+func (m *ItemMatcher) IgnoringExpiration() *ItemMatcher {
+	m.expiration = nil
+	return m
+}
+
+// IgnoringCasID stops comparing the item casID.
+func (m *ItemMatcher) IgnoringCasID() *ItemMatcher {
+	m.casID = nil
+	return m
+}
+
+// Match reports whether arg is a *memcache.Item matching every field set on the matcher.
+func (m *ItemMatcher) Match(arg interface{}) bool {
+	item, ok := arg.(*memcache.Item)
+	return ok && item != nil && len(m.mismatches(item)) == 0
+}
+
+// mismatches returns a description of every field of item not matching the expectation.
+func (m *ItemMatcher) mismatches(item *memcache.Item) []string {
+	var diffs []string
+	if m.key != nil && !m.key.Match(item.Key) {
+		diffs = append(diffs, fmt.Sprintf("expected key %s, but got key %s", m.key, item.Key))
+	}
+	if m.value != nil && !m.value.Match(item.Value) {
+		diffs = append(diffs, fmt.Sprintf("expected value %s, but got value %s", m.value, string(item.Value)))
+	}
+	if m.flags != nil && !m.flags.Match(item.Flags) {
+		diffs = append(diffs, fmt.Sprintf("expected flags %s, but got flags %d", m.flags, item.Flags))
+	}
+	if m.expiration != nil && !m.expiration.Match(item.Expiration) {
+		diffs = append(diffs, fmt.Sprintf("expected expiration %s, but got expiration %d", m.expiration, item.Expiration))
+	}
+	if m.casID != nil && !m.casID.Match(item.CasID) {
+		diffs = append(diffs, fmt.Sprintf("expected casID %s, but got casID %d", m.casID, item.CasID))
+	}
+	return diffs
+}
+
+// String returns string representation
+func (m *ItemMatcher) String() string {
+	var fields []string
+	if m.key != nil {
+		fields = append(fields, fmt.Sprintf("key: %s", m.key))
+	}
+	if m.value != nil {
+		fields = append(fields, fmt.Sprintf("value: %s", m.value))
+	}
+	if m.flags != nil {
+		fields = append(fields, fmt.Sprintf("flags: %s", m.flags))
+	}
+	if m.expiration != nil {
+		fields = append(fields, fmt.Sprintf("expiration: %s", m.expiration))
+	}
+	if m.casID != nil {
+		fields = append(fields, fmt.Sprintf("casID: %s", m.casID))
+	}
+	if len(fields) == 0 {
+		return "any item"
+	}
+	return "item with " + strings.Join(fields, ", ")
+}
+
+// toValueMatcher converts the argument of a With* builder to an item value Matcher.
+func toValueMatcher(value interface{}) Matcher {
+	switch v := value.(type) {
+	case Matcher:
+		return v
+	case string:
+		return toValueMatcher([]byte(v))
+	case []byte:
+		v = append([]byte(nil), v...) // copied, so that the caller may reuse its buffer
+		return &matcherFunc{desc: string(v), fn: func(arg interface{}) bool {
+			b, ok := arg.([]byte)
+			return ok && bytes.Equal(v, b)
+		}}
+	}
+	panic(fmt.Sprintf("memcachemock: value must be a []byte, a string or a Matcher, got %T", value))
+}
+
+// toUint32Matcher converts the argument of a With* builder to an uint32 Matcher.
+func toUint32Matcher(name string, n interface{}) Matcher {
+	if m, ok := n.(Matcher); ok {
+		return m
+	}
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i >= 0 && i <= 1<<32-1 {
+			return Eq(uint32(i))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); u <= 1<<32-1 {
+			return Eq(uint32(u))
+		}
+	}
+	panic(fmt.Sprintf("memcachemock: %s must be an uint32 or a Matcher, got %T(%v)", name, n, n))
+}
@@ -0,0 +1,200 @@
+package memcachemock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnyKey(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectGet().
+		WithKey(AnyKey())
+	_, err := mock.Get("session:1697457600")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestKeyPrefix(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectDelete().
+		WithKey(KeyPrefix("user:"))
+	a.NoError(mock.Delete("user:42"))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestKeyPrefix_Error(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectDelete().
+		WithKey(KeyPrefix("user:"))
+	err := mock.Delete("session:42")
+	a.ErrorContains(err, `expected key with prefix "user:", but got key session:42`)
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestKeySuffixAndContains(t *testing.T) {
+	a := assert.New(t)
+	a.True(KeySuffix(":v2").Match("user:42:v2"))
+	a.False(KeySuffix(":v2").Match("user:42:v1"))
+	a.True(KeyContains(":42:").Match("user:42:v2"))
+	a.False(KeyContains(":42:").Match("user:43:v2"))
+}
+
+func TestKeyRegexp(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectGet().
+		WithKey(KeyRegexp(`^user:[0-9a-f-]{36}$`))
+	_, err := mock.Get("user:not-a-uuid")
+	a.ErrorContains(err, "matching /^user:[0-9a-f-]{36}$/")
+	_, err = mock.Get("user:0b9d1a52-2c4f-4b3e-9f1e-5b6a7c8d9e0f")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestKeyFunc(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectTouch().
+		WithKeyAndSeconds(KeyFunc("lowercase key", func(key string) bool {
+			return key == strings.ToLower(key)
+		}), 10)
+	a.NoError(mock.Touch("some-key", 10))
+	a.NoError(mock.ExpectationsWereMet())
+	a.Contains(mock.expectations[0].String(), "is with key: lowercase key")
+}
+
+func TestKeysMatchers(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectGetMulti().
+		WithKeys(KeysInAnyOrder("a", "b"))
+	mock.ExpectGetMulti().
+		WithKeys(KeysContaining("c"))
+	mock.ExpectGetMulti().
+		WithKeys(AnyKeys())
+	mock.ExpectGetMulti().
+		WithKeys(KeysFunc("two keys", func(keys []string) bool { return len(keys) == 2 }))
+	_, err := mock.GetMulti([]string{"b", "a"})
+	a.NoError(err)
+	_, err = mock.GetMulti([]string{"a", "c"})
+	a.NoError(err)
+	_, err = mock.GetMulti(nil)
+	a.NoError(err)
+	_, err = mock.GetMulti([]string{"a"})
+	a.ErrorContains(err, "expected keys two keys, but got keys [a]")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestDeltaMatchers(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectIncrement().
+		WithKeyAndDelta("counter", AnyDelta()).
+		WillReturnValue(1)
+	mock.ExpectDecrement().
+		WithKeyAndDelta("counter", DeltaBetween(1, 5))
+	mock.ExpectIncrement().
+		WithKeyAndDelta("counter", DeltaFunc("even delta", func(delta uint64) bool { return delta%2 == 0 }))
+	_, err := mock.Increment("counter", 1000)
+	a.NoError(err)
+	_, err = mock.Decrement("counter", 5)
+	a.NoError(err)
+	_, err = mock.Increment("counter", 3)
+	a.ErrorContains(err, "expected call with delta even delta, but got delta 3")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestDeltaBetween_Error(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectDecrement().
+		WithKeyAndDelta("counter", DeltaBetween(1, 5))
+	_, err := mock.Decrement("counter", 6)
+	a.ErrorContains(err, "expected call with delta between 1 and 5, but got delta 6")
+}
+
+func TestSecondsMatchers(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectTouch().
+		WithKeyAndSeconds(KeyPrefix("session:"), AnySeconds())
+	mock.ExpectTouch().
+		WithKeyAndSeconds("some-key", SecondsBetween(60, 120))
+	mock.ExpectTouch().
+		WithKeyAndSeconds("some-key", SecondsFunc("positive", func(seconds int32) bool { return seconds > 0 }))
+	a.NoError(mock.Touch("session:1", -1))
+	a.NoError(mock.Touch("some-key", 90))
+	err := mock.Touch("some-key", 0)
+	a.ErrorContains(err, "expected call with seconds: positive, but got seconds: 0")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestLiteralArgumentsConversion(t *testing.T) {
+	a := assert.New(t)
+	a.True(toDeltaMatcher(10).Match(uint64(10)))
+	a.True(toDeltaMatcher(uint32(10)).Match(uint64(10)))
+	a.True(toSecondsMatcher(int64(10)).Match(int32(10)))
+	a.True(toSecondsMatcher(uint(10)).Match(int32(10)))
+	a.Panics(func() { toKeyMatcher(10) })
+	a.Panics(func() { toKeysMatcher("some-key") })
+	a.Panics(func() { toDeltaMatcher(-1) })
+	a.Panics(func() { toSecondsMatcher(int64(1) << 40) })
+}
+
+func TestMatchersRejectOtherTypes(t *testing.T) {
+	a := assert.New(t)
+	a.False(AnyKey().Match(10))
+	a.False(AnyKeys().Match("some-key"))
+	a.False(AnyDelta().Match(int32(1)))
+	a.False(AnySeconds().Match(uint64(1)))
+	a.True(Any().Match(nil))
+	a.True(Eq([]string{"a"}).Match([]string{"a"}))
+	a.Equal("any value", Any().String())
+}
+
+func TestItemMatcher(t *testing.T) {
+	a := assert.New(t)
+	m := MatchItem().
+		Key(KeyPrefix("user:")).
+		Value("some value").
+		Flags(1).
+		ExpirationBetween(60, 120)
+	a.True(m.Match(&memcache.Item{Key: "user:1", Value: []byte("some value"), Flags: 1, Expiration: 90, CasID: 7}))
+	a.False(m.Match(&memcache.Item{Key: "user:1", Value: []byte("some value"), Flags: 1, Expiration: 10}))
+	a.False(m.Match((*memcache.Item)(nil)))
+	a.False(m.Match("user:1"))
+	a.Equal(`item with key: with prefix "user:", value: some value, flags: 1, expiration: between 60 and 120`, m.String())
+	a.Equal("any item", MatchItem().String())
+}
+
+func TestItemMatcher_Ignoring(t *testing.T) {
+	a := assert.New(t)
+	item := &memcache.Item{Key: "some-key", Flags: 1, Expiration: 10, CasID: 5}
+	m := matchItemFields(item).
+		IgnoringFlags().
+		IgnoringExpiration().
+		IgnoringCasID()
+	a.True(m.Match(&memcache.Item{Key: "some-key", Flags: 2, Expiration: 20, CasID: 6}))
+	a.Panics(func() { MatchItem().Flags(-1) })
+	a.Panics(func() { MatchItem().Value(10) })
+}
+
+func TestItemMatcher_ValueBufferReused(t *testing.T) {
+	a := assert.New(t)
+	buf := []byte("some value")
+	m := MatchItem().Value(buf)
+	mock := New()
+	mock.ExpectSet().WithItemKey("some-key").WithItemValue(buf)
+	copy(buf, "other")
+
+	a.True(m.Match(&memcache.Item{Value: []byte("some value")}))
+	a.Equal("item with value: some value", m.String())
+	a.NoError(mock.Set(&memcache.Item{Key: "some-key", Value: []byte("some value")}))
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -1,30 +1,122 @@
 /*
 The package memcachemock is used to mock a memcache client.
+
+A mock is safe for concurrent use: expectations may be declared while other goroutines call the mock,
+and each call matches and fulfills an expectation atomically, so an expectation is never consumed
+more times than planned. An expectation should however not be modified once calls may match it.
 */
 package memcachemock
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 )
 
-func New(server ...string) *memcachemock {
-	mock := &memcachemock{}
+// New returns a mock routing keys to the given servers the same way memcache.New does.
+// Without servers, keys are not routed. The servers are not resolved, and are named as given.
+func New(server ...string) *Mock {
+	mock := &Mock{ordered: true, selector: newServerSelector(server)}
 	return mock
 }
 
-func NewFromSelector(ss *memcache.ServerSelector) *memcachemock {
-	mock := &memcachemock{}
+// NewFromSelector returns a mock routing keys with the given selector, as memcache.NewFromSelector does.
+func NewFromSelector(ss *memcache.ServerSelector) *Mock {
+	mock := &Mock{ordered: true}
+	if ss != nil {
+		mock.selector = *ss
+	}
+	return mock
+}
+
+// NewT returns a mock bound to the test t.
+// Unexpected calls are reported with t.Errorf, pointing at the file and line of the call,
+// and the expectations are verified when the test and its subtests complete.
+func NewT(t testing.TB, opts ...Option) *Mock {
+	mock := &Mock{ordered: true, t: t}
+	for _, opt := range opts {
+		opt(mock)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
 	return mock
 }
 
+// Option configures a mock created with NewT, or with New and With.
+type Option func(*Mock)
+
+// FailFast stops the test with t.Fatalf on the first unexpected call,
+// instead of only reporting it. As t.Fatalf, it must only be used when
+// the mock is called from the goroutine running the test.
+func FailFast() Option {
+	return func(c *Mock) {
+		c.failFast = true
+	}
+}
+
 type gomemcacheMockIface interface {
 	// ExpectationsWereMet checks whether all queued expectations
 	// were met in order (unless MatchExpectationsInOrder set to false).
-	// If any of them was not met - an error is returned.
+	// If any of them was not met - an *UnmetExpectationsError listing all of them is returned.
 	ExpectationsWereMet() error
 
+	// MatchExpectationsInOrder gives an option whether to match all
+	// expectations in the order they were set or not.
+	//
+	// By default it is set to - true. But if you use goroutines
+	// to parallelize your cache calls, this option may be handy.
+	MatchExpectationsInOrder(bool)
+
+	// InOrder declares that the given expectations must be met one after
+	// the other, whatever the MatchExpectationsInOrder option is.
+	// An expectation cannot be matched before the previous one is met.
+	// It panics if an expectation would have to happen after itself.
+	// The ordering must not change once calls have started.
+	InOrder(expectations ...Expectation)
+
+	// InAnyOrder declares that the given expectations may be met in any order
+	// among themselves, even when expectations are matched in order.
+	// The expectations must be declared one after the other, on this mock: it panics otherwise.
+	// The ordering must not change once calls have started.
+	InAnyOrder(expectations ...Expectation)
+
+	// Reset drops every expectation and recorded call, keeping the options, ordering and servers of the mock.
+	Reset()
+
+	// Pending returns the expectations which were not called enough times yet, in order.
+	Pending() []UnmetExpectation
+
+	// Snapshot captures copies of the expectations, how many times they were called,
+	// whether they are matched in order, and the recorded calls.
+	Snapshot() *ExpectationsSnapshot
+
+	// Restore replaces the expectations and recorded calls by copies of the ones captured by Snapshot.
+	Restore(s *ExpectationsSnapshot)
+
+	// Calls returns every call made to the mock, expected or not, in order.
+	Calls() []Call
+
+	// CallsTo returns the calls made to the given method, e.g. "Get", in order.
+	CallsTo(method string) []Call
+
+	// AssertCalled asserts that method was called with the given arguments,
+	// each being a literal value or a Matcher.
+	AssertCalled(t TestingT, method string, args ...interface{}) bool
+
+	// AssertNotCalled asserts that method was never called with the given arguments,
+	// each being a literal value or a Matcher.
+	AssertNotCalled(t TestingT, method string, args ...interface{}) bool
+
+	// AssertNumberOfCalls asserts that method was called exactly n times.
+	AssertNumberOfCalls(t TestingT, method string, n int) bool
+
 	// ExpectAdd expects Add() to be called with memcache.Item.
 	// The *ExpectedAdd allows to mock the response.
 	ExpectAdd() *ExpectedAdd
@@ -92,6 +184,12 @@ type gomemcacheMockIface interface {
 
 type gomemcacheIface interface {
 	gomemcacheMockIface
+	Client
+}
+
+// Client is the method set of *memcache.Client.
+// Code depending on Client rather than *memcache.Client can be given a Mock or a Fake in tests.
+type Client interface {
 	Add(item *memcache.Item) error
 	Append(item *memcache.Item) error
 	Close() error
@@ -110,227 +208,459 @@ type gomemcacheIface interface {
 	Touch(key string, seconds int32) (err error)
 }
 
-var _ gomemcacheIface
+var (
+	_ Client          = (*memcache.Client)(nil)
+	_ gomemcacheIface = (*Mock)(nil)
+)
 
-type memcachemock struct {
+// Mock is a memcache client asserting the calls made to it against expectations.
+// It implements Client.
+type Mock struct {
+	t            testing.TB // reports unexpected calls, if set
+	failFast     bool       // stops the test on the first unexpected call
+	strict       bool       // validates keys and values as a *memcache.Client
+	mu           sync.Mutex // guards ordered, expectations and failures, held while matching a call
+	ordered      bool
 	expectations []Expectation
+	selector     memcache.ServerSelector // routes keys to servers, if set
+	failures     map[string]error        // errors injected on servers, by address
+	calls        []*Call
+	callsMu      sync.Mutex
+}
+
+func (c *Mock) MatchExpectationsInOrder(b bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ordered = b
+}
+
+func (c *Mock) InOrder(expectations ...Expectation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, before := range expectations {
+		for _, after := range expectations[i+1:] {
+			if after == before || dependsOn(before, after) {
+				panic(fmt.Sprintf("memcachemock: InOrder would make %q happen after itself", summary(after)))
+			}
+		}
+	}
+	for i := 1; i < len(expectations); i++ {
+		expectations[i].Lock()
+		expectations[i].addPrerequisite(expectations[i-1])
+		expectations[i].Unlock()
+	}
+}
+
+func (c *Mock) InAnyOrder(expectations ...Expectation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	positions := map[int]bool{}
+	first, last := len(c.expectations), -1
+	for _, e := range expectations {
+		i := c.indexOf(e)
+		if i < 0 {
+			panic(fmt.Sprintf("memcachemock: InAnyOrder got %q, which was not declared on this mock", summary(e)))
+		}
+		positions[i] = true
+		if i < first {
+			first = i
+		}
+		if i > last {
+			last = i
+		}
+	}
+	for i := first; i <= last; i++ {
+		if !positions[i] {
+			panic(fmt.Sprintf("memcachemock: InAnyOrder expectations must be declared one after the other, but %q is declared among them", summary(c.expectations[i])))
+		}
+	}
+	group := &anyOrderGroup{}
+	for _, e := range expectations {
+		e.Lock()
+		e.setAnyOrderGroup(group)
+		e.Unlock()
+	}
 }
 
-func (c *memcachemock) ExpectationsWereMet() error {
+func (c *Mock) ExpectationsWereMet() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var unmet []UnmetExpectation
 	for _, e := range c.expectations {
-		fulfilled := e.fulfilled() || !e.required()
-		if !fulfilled {
-			return fmt.Errorf("there is a remaining expectation which was not matched: %s", e)
+		e.Lock()
+		if called, min, max := e.calls(); called < min || called > max {
+			unmet = append(unmet, UnmetExpectation{Expectation: e, Called: called, Min: min, Max: max})
 		}
+		e.Unlock()
+	}
+	if len(unmet) > 0 {
+		return &UnmetExpectationsError{Unmet: unmet}
 	}
 	return nil
 }
 
+// indexOf returns the position of e among the expectations of the mock, or -1. The caller must hold c.mu.
+func (c *Mock) indexOf(e Expectation) int {
+	for i, ex := range c.expectations {
+		if ex == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// expect queues the expectation e
+func (c *Mock) expect(e Expectation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expectations = append(c.expectations, e)
+}
+
 // Expectations Definition Methods
-func (c *memcachemock) ExpectAdd() *ExpectedAdd {
+func (c *Mock) ExpectAdd() *ExpectedAdd {
 	e := &ExpectedAdd{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectAppend() *ExpectedAppend {
+func (c *Mock) ExpectAppend() *ExpectedAppend {
 	e := &ExpectedAppend{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectClose() *ExpectedClose {
+func (c *Mock) ExpectClose() *ExpectedClose {
 	e := &ExpectedClose{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectCompareAndSwap() *ExpectedCompareAndSwap {
+func (c *Mock) ExpectCompareAndSwap() *ExpectedCompareAndSwap {
 	e := &ExpectedCompareAndSwap{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectDecrement() *ExpectedDecrement {
+func (c *Mock) ExpectDecrement() *ExpectedDecrement {
 	e := &ExpectedDecrement{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectDelete() *ExpectedDelete {
+func (c *Mock) ExpectDelete() *ExpectedDelete {
 	e := &ExpectedDelete{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectDeleteAll() *ExpectedDeleteAll {
+func (c *Mock) ExpectDeleteAll() *ExpectedDeleteAll {
 	e := &ExpectedDeleteAll{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectFlushAll() *ExpectedFlushAll {
+func (c *Mock) ExpectFlushAll() *ExpectedFlushAll {
 	e := &ExpectedFlushAll{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectGet() *ExpectedGet {
+func (c *Mock) ExpectGet() *ExpectedGet {
 	e := &ExpectedGet{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectGetMulti() *ExpectedGetMulti {
+func (c *Mock) ExpectGetMulti() *ExpectedGetMulti {
 	e := &ExpectedGetMulti{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectIncrement() *ExpectedIncrement {
+func (c *Mock) ExpectIncrement() *ExpectedIncrement {
 	e := &ExpectedIncrement{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectPing() *ExpectedPing {
+func (c *Mock) ExpectPing() *ExpectedPing {
 	e := &ExpectedPing{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectPrepend() *ExpectedPrepend {
+func (c *Mock) ExpectPrepend() *ExpectedPrepend {
 	e := &ExpectedPrepend{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectReplace() *ExpectedReplace {
+func (c *Mock) ExpectReplace() *ExpectedReplace {
 	e := &ExpectedReplace{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectSet() *ExpectedSet {
+func (c *Mock) ExpectSet() *ExpectedSet {
 	e := &ExpectedSet{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
-func (c *memcachemock) ExpectTouch() *ExpectedTouch {
+func (c *Mock) ExpectTouch() *ExpectedTouch {
 	e := &ExpectedTouch{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 // Memcache Methods Mocks
-func (c *memcachemock) Add(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedAdd](c, "Add()", func(addExp *ExpectedAdd) error {
+func (c *Mock) Add(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Add", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedAdd](c, call, "Add()", func(addExp *ExpectedAdd) error {
 		if err := addExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := addExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Append(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedAppend](c, "Append()", func(appendExp *ExpectedAppend) error {
+func (c *Mock) Append(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Append", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedAppend](c, call, "Append()", func(appendExp *ExpectedAppend) error {
 		if err := appendExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := appendExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Close() (err error) {
-	ex, err := findExpectation[*ExpectedClose](c, "Close()")
+func (c *Mock) Close() (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Close")
+	defer func() { c.finishCall(call, err) }()
+	ex, n, err := findExpectation[*ExpectedClose](c, call, "Close()")
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond()
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) CompareAndSwap(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedCompareAndSwap](c, "CompareAndSwap()", func(compareAndSwapExp *ExpectedCompareAndSwap) error {
+func (c *Mock) CompareAndSwap(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("CompareAndSwap", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedCompareAndSwap](c, call, "CompareAndSwap()", func(compareAndSwapExp *ExpectedCompareAndSwap) error {
 		if err := compareAndSwapExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := compareAndSwapExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Decrement(key string, delta uint64) (newValue uint64, err error) {
-	ex, err := findExpectationFunc[*ExpectedDecrement](c, "Decrement()", func(decrementExp *ExpectedDecrement) error {
+func (c *Mock) Decrement(key string, delta uint64) (newValue uint64, err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Decrement", key, delta)
+	defer func() { c.finishCall(call, err, newValue) }()
+	server, err := c.route(call, key)
+	if err != nil {
+		return 0, err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedDecrement](c, call, "Decrement()", func(decrementExp *ExpectedDecrement) error {
 		if err := decrementExp.keyMatches(key); err != nil {
 			return err
 		}
 		if err := decrementExp.deltaMatches(delta); err != nil {
 			return err
 		}
+		if err := decrementExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return 0, err
 	}
-	return ex.value, ex.error()
+	if ex.respond != nil {
+		return ex.respond(key, delta)
+	}
+	value, err := ex.response(n, ex.value)
+	newValue, _ = value.(uint64)
+	return newValue, err
 }
 
-func (c *memcachemock) Delete(key string) (err error) {
-	ex, err := findExpectationFunc[*ExpectedDelete](c, "Delete()", func(deleteExp *ExpectedDelete) error {
+func (c *Mock) Delete(key string) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Delete", key)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.route(call, key)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedDelete](c, call, "Delete()", func(deleteExp *ExpectedDelete) error {
 		if err := deleteExp.keyMatches(key); err != nil {
 			return err
 		}
+		if err := deleteExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(key)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) DeleteAll() (err error) {
-	ex, err := findExpectation[*ExpectedDeleteAll](c, "DeleteAll()")
+func (c *Mock) DeleteAll() (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("DeleteAll")
+	defer func() { c.finishCall(call, err) }()
+	// as with a *memcache.Client, only the server the empty key is routed to is reached
+	if _, err := c.route(call, ""); err != nil {
+		return err
+	}
+	ex, n, err := findExpectation[*ExpectedDeleteAll](c, call, "DeleteAll()")
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond()
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) FlushAll() (err error) {
-	ex, err := findExpectation[*ExpectedFlushAll](c, "FlushAll()")
+func (c *Mock) FlushAll() (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("FlushAll")
+	defer func() { c.finishCall(call, err) }()
+	if err := c.failingServer(); err != nil {
+		return err
+	}
+	ex, n, err := findExpectation[*ExpectedFlushAll](c, call, "FlushAll()")
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond()
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Get(key string) (item *memcache.Item, err error) {
-	ex, err := findExpectationFunc[*ExpectedGet](c, "Get()", func(getExp *ExpectedGet) error {
+func (c *Mock) Get(key string) (item *memcache.Item, err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Get", key)
+	defer func() { c.finishCall(call, err, item) }()
+	server, err := c.route(call, key)
+	if err != nil {
+		return nil, err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedGet](c, call, "Get()", func(getExp *ExpectedGet) error {
 		if err := getExp.keyMatches(key); err != nil {
 			return err
 		}
+		if err := getExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return ex.item, ex.error()
+	if ex.respond != nil {
+		return ex.respond(key)
+	}
+	value, err := ex.response(n, ex.item)
+	item, _ = value.(*memcache.Item)
+	return item, err
 }
 
-func (c *memcachemock) GetMulti(keys []string) (items map[string]*memcache.Item, err error) {
-	ex, err := findExpectationFunc[*ExpectedGetMulti](c, "GetMulti()", func(getMultiExp *ExpectedGetMulti) error {
+func (c *Mock) GetMulti(keys []string) (items map[string]*memcache.Item, err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("GetMulti", keys)
+	defer func() { c.finishCall(call, err, items) }()
+	if err := c.routeKeys(keys); err != nil {
+		return nil, err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedGetMulti](c, call, "GetMulti()", func(getMultiExp *ExpectedGetMulti) error {
 		if err := getMultiExp.keysMatch(keys); err != nil {
 			return err
 		}
@@ -339,86 +669,184 @@ func (c *memcachemock) GetMulti(keys []string) (items map[string]*memcache.Item,
 	if err != nil {
 		return nil, err
 	}
-	return ex.items, ex.error()
+	if ex.respond != nil {
+		return ex.respond(keys)
+	}
+	value, err := ex.response(n, ex.items)
+	items, _ = value.(map[string]*memcache.Item)
+	return items, err
 }
 
-func (c *memcachemock) Increment(key string, delta uint64) (newValue uint64, err error) {
-	ex, err := findExpectationFunc[*ExpectedIncrement](c, "Increment()", func(incrementExp *ExpectedIncrement) error {
+func (c *Mock) Increment(key string, delta uint64) (newValue uint64, err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Increment", key, delta)
+	defer func() { c.finishCall(call, err, newValue) }()
+	server, err := c.route(call, key)
+	if err != nil {
+		return 0, err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedIncrement](c, call, "Increment()", func(incrementExp *ExpectedIncrement) error {
 		if err := incrementExp.keyMatches(key); err != nil {
 			return err
 		}
 		if err := incrementExp.deltaMatches(delta); err != nil {
 			return err
 		}
+		if err := incrementExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return 0, err
 	}
-	return ex.value, ex.error()
+	if ex.respond != nil {
+		return ex.respond(key, delta)
+	}
+	value, err := ex.response(n, ex.value)
+	newValue, _ = value.(uint64)
+	return newValue, err
 }
 
-func (c *memcachemock) Ping() (err error) {
-	ex, err := findExpectation[*ExpectedPing](c, "Ping()")
+func (c *Mock) Ping() (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Ping")
+	defer func() { c.finishCall(call, err) }()
+	if err := c.failingServer(); err != nil {
+		return err
+	}
+	ex, n, err := findExpectation[*ExpectedPing](c, call, "Ping()")
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond()
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Prepend(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedPrepend](c, "Prepend()", func(prependExp *ExpectedPrepend) error {
+func (c *Mock) Prepend(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Prepend", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedPrepend](c, call, "Prepend()", func(prependExp *ExpectedPrepend) error {
 		if err := prependExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := prependExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Replace(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedReplace](c, "Replace()", func(replaceExp *ExpectedReplace) error {
+func (c *Mock) Replace(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Replace", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedReplace](c, call, "Replace()", func(replaceExp *ExpectedReplace) error {
 		if err := replaceExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := replaceExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Set(item *memcache.Item) (err error) {
-	ex, err := findExpectationFunc[*ExpectedSet](c, "Set()", func(setExp *ExpectedSet) error {
+func (c *Mock) Set(item *memcache.Item) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Set", item)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.routeItem(call, item)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedSet](c, call, "Set()", func(setExp *ExpectedSet) error {
 		if err := setExp.itemMatches(item); err != nil {
 			return err
 		}
+		if err := setExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(item)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
-func (c *memcachemock) Touch(key string, seconds int32) (err error) {
-	ex, err := findExpectationFunc[*ExpectedTouch](c, "Touch()", func(touchExp *ExpectedTouch) error {
+func (c *Mock) Touch(key string, seconds int32) (err error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	call := c.startCall("Touch", key, seconds)
+	defer func() { c.finishCall(call, err) }()
+	server, err := c.route(call, key)
+	if err != nil {
+		return err
+	}
+	ex, n, err := findExpectationFunc[*ExpectedTouch](c, call, "Touch()", func(touchExp *ExpectedTouch) error {
 		if err := touchExp.keyMatches(key); err != nil {
 			return err
 		}
 		if err := touchExp.secondsMatch(seconds); err != nil {
 			return err
 		}
+		if err := touchExp.serverMatches(server); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
-	return ex.error()
+	if ex.respond != nil {
+		return ex.respond(key, seconds)
+	}
+	_, err = ex.response(n, nil)
+	return err
 }
 
 type ExpectationType[t any] interface {
@@ -426,49 +854,218 @@ type ExpectationType[t any] interface {
 	Expectation
 }
 
-func findExpectationFunc[ET ExpectationType[t], t any](c *memcachemock, method string, cmp func(ET) error) (ET, error) {
+// findExpectationFunc returns the expectation matching a call to method,
+// along with how many times it was matched before.
+func findExpectationFunc[ET ExpectationType[t], t any](c *Mock, call *Call, method string, cmp func(ET) error) (ET, uint, error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	c.mu.Lock()
+	expected, n, err := matchExpectation[ET](c, method, cmp)
+	c.mu.Unlock()
+	if err != nil {
+		c.unexpectedCall(call, err)
+		return expected, 0, err
+	}
+	expected.Lock()
+	delay, unblock := expected.latency()
+	do, panics, panicValue := expected.sideEffects()
+	expected.Unlock()
+	wait(delay, unblock)
+	if len(do) > 0 {
+		args := call.args()
+		for _, fn := range do {
+			fn(args)
+		}
+	}
+	if panics {
+		c.panicCall(call, panicValue)
+		panic(panicValue)
+	}
+	return expected, n, nil
+}
+
+// wait blocks until unblock is ready, if set, then sleeps for delay.
+// The mock is not locked meanwhile, so that other calls are not delayed.
+func wait(delay time.Duration, unblock <-chan struct{}) {
+	if unblock != nil {
+		<-unblock
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+func findExpectation[ET ExpectationType[t], t any](c *Mock, call *Call, method string) (ET, uint, error) {
+	if c.t != nil {
+		c.t.Helper()
+	}
+	return findExpectationFunc[ET, t](c, call, method, func(_ ET) error { return nil })
+}
+
+// matchExpectation fulfills the expectation matching a call to method. The caller must hold c.mu,
+// so that two concurrent calls cannot both match an expectation awaiting a single call.
+// A call matching no other expectation fails with the Never expectation it matches, if any.
+func matchExpectation[ET ExpectationType[t], t any](c *Mock, method string, cmp func(ET) error) (ET, uint, error) {
+	expected, n, err := scanExpectations[ET](c, method, cmp)
+	if err != nil {
+		if neverErr := neverViolation[ET](c, method, cmp); neverErr != nil {
+			return nil, 0, neverErr
+		}
+	}
+	return expected, n, err
+}
+
+// scanExpectations fulfills the expectation matching a call to method, following the ordering of the expectations.
+// The caller must hold c.mu.
+func scanExpectations[ET ExpectationType[t], t any](c *Mock, method string, cmp func(ET) error) (ET, uint, error) {
 	var expected ET
 	var fulfilled int
 	var ok bool
-	var err error
+	var err, mismatch, violation error
+	var block *anyOrderGroup // group of expectations skipped while matching in order
 	for _, next := range c.expectations {
 		next.Lock()
-		if next.fulfilled() {
+		if next.saturated() {
 			next.Unlock()
 			fulfilled++
 			continue
 		}
 
+		if c.ordered && block != nil && next.anyOrderGroup() != block {
+			next.Unlock()
+			break
+		}
+
+		err = nil
 		if expected, ok = next.(ET); ok {
 			err = cmp(expected)
+			if err == nil {
+				if err = orderingViolation(next); err != nil && violation == nil {
+					violation = err
+				}
+			}
 			if err == nil {
 				break
 			}
+			mismatch = err
+			expected = nil
 		}
-		if (!ok || err != nil) && !next.required() {
+		if !c.ordered {
+			next.Unlock()
+			continue
+		}
+		if (!ok || err != nil) && next.satisfied() {
+			next.Unlock()
+			continue
+		}
+		if group := next.anyOrderGroup(); group != nil {
+			if mismatch == nil {
+				mismatch = fmt.Errorf("call to method %s, was not expected, next expectation is: %s", method, next)
+			}
+			block = group
 			next.Unlock()
 			continue
 		}
 		next.Unlock()
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		return nil, fmt.Errorf("call to method %s, was not expected, next expectation is: %s", method, next)
+		return nil, 0, fmt.Errorf("call to method %s, was not expected, next expectation is: %s", method, next)
 	}
 
 	if expected == nil {
+		// an ordering violation names the broken edge, it takes precedence over plain mismatches
+		if violation != nil {
+			return nil, 0, violation
+		}
+		if mismatch != nil {
+			return nil, 0, mismatch
+		}
 		msg := fmt.Sprintf("call to method %s was not expected", method)
 		if fulfilled == len(c.expectations) {
 			msg = "all expectations were already fulfilled, " + msg
 		}
-		return nil, fmt.Errorf(msg)
+		return nil, 0, fmt.Errorf(msg)
 	}
 	defer expected.Unlock()
 
-	expected.fulfill()
-	return expected, nil
+	return expected, expected.fulfill(), nil
+}
+
+// neverViolation returns an error if the call matches an expectation which should never be called,
+// wherever it is declared. It is only checked for calls matching no other expectation. The call is counted, so that ExpectationsWereMet reports it as well.
+func neverViolation[ET ExpectationType[t], t any](c *Mock, method string, cmp func(ET) error) error {
+	for _, next := range c.expectations {
+		next.Lock()
+		if _, _, max := next.calls(); max == 0 {
+			if ex, ok := next.(ET); ok && cmp(ex) == nil {
+				next.fulfill()
+				next.Unlock()
+				return fmt.Errorf("call to method %s was expected to never happen: %s", method, next)
+			}
+		}
+		next.Unlock()
+	}
+	return nil
 }
 
-func findExpectation[ET ExpectationType[t], t any](c *memcachemock, method string) (ET, error) {
-	return findExpectationFunc[ET, t](c, method, func(_ ET) error { return nil })
+// orderingViolation returns an error naming the first prerequisite of e
+// which is not met yet, as declared with InOrder.
+// The caller must hold c.mu, which serializes matching: the prerequisites are read without
+// taking their lock, as the lock of e is already held. This is safe as InOrder and InAnyOrder
+// only change prerequisites and groups under c.mu, and the ordering must not change once calls have started.
+func orderingViolation(e Expectation) error {
+	for _, before := range e.prerequisites() {
+		if !before.satisfied() {
+			return fmt.Errorf("ordering violated: %q must happen after %q, which was not met yet", summary(e), summary(before))
+		}
+	}
+	return nil
+}
+
+// dependsOn tells whether e must happen after target, directly or through other prerequisites.
+// The caller must hold c.mu.
+func dependsOn(e, target Expectation) bool {
+	seen := map[Expectation]bool{}
+	var visit func(Expectation) bool
+	visit = func(e Expectation) bool {
+		for _, before := range e.prerequisites() {
+			if before == target {
+				return true
+			}
+			if !seen[before] {
+				seen[before] = true
+				if visit(before) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return visit(e)
+}
+
+// summary returns the string representation of an expectation on a single line
+func summary(e Expectation) string {
+	lines := strings.Split(strings.TrimSpace(e.String()), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(lines[i]), "- "), ":")
+	}
+	return strings.Join(lines, ", ")
+}
+
+// unexpectedCall reports a call which did not match any expectation to the test, if any.
+// The mocked methods and the functions up to this one are marked as helpers,
+// so that the test reports the failure at the line the mock was called from.
+func (c *Mock) unexpectedCall(call *Call, err error) {
+	if c.t == nil {
+		return
+	}
+	c.t.Helper()
+	if c.failFast {
+		c.t.Fatalf("unexpected call %s: %s", call.signature(), err)
+		return
+	}
+	c.t.Errorf("unexpected call %s: %s", call.signature(), err)
 }
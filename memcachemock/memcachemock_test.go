@@ -1,12 +1,23 @@
 package memcachemock
 
 import (
+	"fmt"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestClient(t *testing.T) {
+	a := assert.New(t)
+	clients := []Client{memcache.New("localhost:11211"), New("localhost:11211"), NewFake()}
+	for _, client := range clients {
+		a.NotNil(client)
+	}
+}
+
 func TestNewFromSelector(t *testing.T) {
 	var ss memcache.ServerSelector
 	mock := NewFromSelector(&ss)
@@ -494,3 +505,440 @@ func TestTouch_ErrorSeconds(t *testing.T) {
 	a.Error(err)
 	a.Error(mock.ExpectationsWereMet())
 }
+
+func TestMatchExpectationsInOrder(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectGet().
+		WithKey("first-key")
+	mock.ExpectGet().
+		WithKey("second-key")
+	_, err := mock.Get("second-key")
+	a.ErrorContains(err, "expected key first-key, but got key second-key")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestMatchExpectationsInAnyOrder(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	first := &memcache.Item{Key: "first-key"}
+	second := &memcache.Item{Key: "second-key"}
+	mock.ExpectGet().
+		WithKey("first-key").
+		WillReturnItem(first)
+	mock.ExpectPing()
+	mock.ExpectGet().
+		WithKey("second-key").
+		WillReturnItem(second)
+	item, err := mock.Get("second-key")
+	a.NoError(err)
+	a.Equal(second, item)
+	item, err = mock.Get("first-key")
+	a.NoError(err)
+	a.Equal(first, item)
+	a.Error(mock.ExpectationsWereMet())
+	a.NoError(mock.Ping())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestMatchExpectationsInAnyOrder_Unexpected(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectGet().
+		WithKey("some-key")
+	mock.ExpectPing()
+	_, err := mock.Get("another-key")
+	a.ErrorContains(err, "expected key some-key, but got key another-key")
+	err = mock.Close()
+	a.ErrorContains(err, "call to method Close() was not expected")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestMatchExpectationsInAnyOrder_Concurrent(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	keys := []string{"a", "b", "c", "d"}
+	for _, key := range keys {
+		mock.ExpectGet().
+			WithKey(key).
+			WillReturnItem(&memcache.Item{Key: key})
+	}
+	errs := make(chan error, len(keys))
+	for _, key := range keys {
+		go func(key string) {
+			_, err := mock.Get(key)
+			errs <- err
+		}(key)
+	}
+	for range keys {
+		a.NoError(<-errs)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestOptionalMismatchIsNotFulfilled(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectGet().
+		WithKey("some-key").
+		Maybe()
+	_, err := mock.Get("another-key")
+	a.ErrorContains(err, "expected key some-key, but got key another-key")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInOrder(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	getA := mock.ExpectGet().WithKey("a")
+	setA := mock.ExpectSet().WithItemKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	setB := mock.ExpectSet().WithItemKey("b")
+	mock.InOrder(getA, setA)
+	mock.InOrder(getB, setB)
+
+	_, err := mock.Get("b")
+	a.NoError(err)
+	_, err = mock.Get("a")
+	a.NoError(err)
+	a.NoError(mock.Set(&memcache.Item{Key: "b"}))
+	a.NoError(mock.Set(&memcache.Item{Key: "a"}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInOrder_Violated(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	getA := mock.ExpectGet().WithKey("a")
+	setA := mock.ExpectSet().WithItemKey("a")
+	mock.InOrder(getA, setA)
+
+	err := mock.Set(&memcache.Item{Key: "a"})
+	a.EqualError(err, `ordering violated: "ExpectedSet => expecting call to Set(), is with item with key: a" `+
+		`must happen after "ExpectedGet => expecting call to Get(), is with key: a", which was not met yet`)
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestInOrder_ViolatedAmongCandidates(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	getA := mock.ExpectGet().WithKey("a")
+	setA := mock.ExpectSet().WithItemKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	setB := mock.ExpectSet().WithItemKey("b")
+	mock.InOrder(getA, setA)
+	mock.InOrder(getB, setB)
+
+	err := mock.Set(&memcache.Item{Key: "a"})
+	a.EqualError(err, `ordering violated: "ExpectedSet => expecting call to Set(), is with item with key: a" `+
+		`must happen after "ExpectedGet => expecting call to Get(), is with key: a", which was not met yet`)
+}
+
+func TestInOrder_OptionalPrerequisite(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	ping := mock.ExpectPing()
+	ping.Maybe()
+	closeExp := mock.ExpectClose()
+	mock.InOrder(ping, closeExp)
+	a.NoError(mock.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInOrder_SelfPrerequisite(t *testing.T) {
+	mock := New()
+	a := assert.New(t)
+	get := mock.ExpectGet().WithKey("k")
+	a.PanicsWithValue(`memcachemock: InOrder would make "ExpectedGet => expecting call to Get(), is with key: k" happen after itself`, func() {
+		mock.InOrder(get, get)
+	})
+	_, err := mock.Get("k")
+	a.NoError(err)
+}
+
+func TestInOrder_Cycle(t *testing.T) {
+	mock := New()
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	get := mock.ExpectGet().WithKey("k")
+	set := mock.ExpectSet().WithItemKey("k")
+	del := mock.ExpectDelete().WithKey("k")
+	mock.InOrder(get, set)
+	mock.InOrder(set, del)
+	a.Panics(func() { mock.InOrder(del, get) })
+	a.Panics(func() { mock.InOrder(get, set, get) })
+
+	_, err := mock.Get("k")
+	a.NoError(err)
+	a.NoError(mock.Set(&memcache.Item{Key: "k"}))
+	a.NoError(mock.Delete("k"))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInAnyOrder(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	mock.ExpectPing()
+	getA := mock.ExpectGet().WithKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	getC := mock.ExpectGet().WithKey("c")
+	mock.InAnyOrder(getA, getB, getC)
+	mock.ExpectClose()
+
+	a.NoError(mock.Ping())
+	_, err := mock.Get("c")
+	a.NoError(err)
+	_, err = mock.Get("a")
+	a.NoError(err)
+	_, err = mock.Get("b")
+	a.NoError(err)
+	a.NoError(mock.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInAnyOrder_NotContiguous(t *testing.T) {
+	mock := New()
+	a := assert.New(t)
+	getA := mock.ExpectGet().WithKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	getC := mock.ExpectGet().WithKey("c")
+	a.PanicsWithValue(`memcachemock: InAnyOrder expectations must be declared one after the other, `+
+		`but "ExpectedGet => expecting call to Get(), is with key: b" is declared among them`, func() {
+		mock.InAnyOrder(getA, getC)
+	})
+	a.Panics(func() { mock.InAnyOrder(getA, New().ExpectPing()) })
+	a.NotPanics(func() { mock.InAnyOrder(getC, getA, getB, getA) }, "order and duplicates do not matter")
+}
+
+func TestInAnyOrder_SeparateGroups(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	getA := mock.ExpectGet().WithKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	mock.InAnyOrder(getA, getB)
+	getC := mock.ExpectGet().WithKey("c")
+	getD := mock.ExpectGet().WithKey("d")
+	mock.InAnyOrder(getC, getD)
+
+	_, err := mock.Get("c")
+	a.Error(err, "the second group should not be matched before the first one")
+	_, err = mock.Get("b")
+	a.NoError(err)
+	_, err = mock.Get("a")
+	a.NoError(err)
+	_, err = mock.Get("d")
+	a.NoError(err)
+	_, err = mock.Get("c")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInAnyOrder_BlockIsABarrier(t *testing.T) {
+	mock := New("localhost:11211")
+	a := assert.New(t)
+	getA := mock.ExpectGet().WithKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	mock.InAnyOrder(getA, getB)
+	mock.ExpectClose()
+
+	_, err := mock.Get("b")
+	a.NoError(err)
+	err = mock.Close()
+	a.ErrorContains(err, "call to method Close(), was not expected, next expectation is: ExpectedGet")
+	_, err = mock.Get("z")
+	a.ErrorContains(err, "expected key a, but got key z")
+	_, err = mock.Get("a")
+	a.NoError(err)
+	a.NoError(mock.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+// fakeTB records what a mock reports to its test
+type fakeTB struct {
+	testing.TB
+	errors    []string
+	locations []string // file and line each error is reported at, skipping helpers as testing does
+	helpers   map[string]bool
+	fatal     bool
+	cleanups  []func()
+}
+
+func (t *fakeTB) Helper() {
+	pc, _, _, _ := runtime.Caller(1)
+	if t.helpers == nil {
+		t.helpers = map[string]bool{}
+	}
+	t.helpers[runtime.FuncForPC(pc).Name()] = true
+}
+
+func (t *fakeTB) Errorf(format string, args ...interface{}) {
+	t.Helper()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+	pcs := make([]uintptr, 50)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !t.helpers[frame.Function] || !more {
+			t.locations = append(t.locations, fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line))
+			return
+		}
+	}
+}
+
+func (t *fakeTB) Fatalf(format string, args ...interface{}) {
+	t.Helper()
+	t.Errorf(format, args...)
+	t.fatal = true
+}
+
+func (t *fakeTB) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *fakeTB) cleanup() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestNewT_VerifiesExpectationsOnCleanup(t *testing.T) {
+	a := assert.New(t)
+	tb := &fakeTB{}
+	mock := NewT(tb)
+	mock.ExpectGet().WithKey("foo")
+	mock.ExpectDelete().WithKey("bar")
+
+	_, err := mock.Get("foo")
+	a.NoError(err)
+	a.Empty(tb.errors)
+
+	tb.cleanup()
+	if a.Len(tb.errors, 1) {
+		a.Contains(tb.errors[0], "there were unfulfilled expectations")
+		a.Contains(tb.errors[0], "Delete()")
+	}
+}
+
+func TestNewT_ReportsUnexpectedCallsWithCaller(t *testing.T) {
+	a := assert.New(t)
+	tb := &fakeTB{}
+	mock := NewT(tb)
+	mock.ExpectGet().WithKey("foo")
+
+	_, file, line, _ := runtime.Caller(0)
+	_, err := mock.Get("bar")
+	a.Error(err)
+	if a.Len(tb.errors, 1) {
+		a.Contains(tb.errors[0], `unexpected call Get("bar")`)
+		a.Equal(fmt.Sprintf("%s:%d", filepath.Base(file), line+1), tb.locations[0])
+		a.Contains(tb.errors[0], "expected key foo, but got key bar")
+	}
+	a.False(tb.fatal)
+
+	tb.cleanup()
+	a.Len(tb.errors, 2)
+}
+
+func TestNewT_FailFast(t *testing.T) {
+	a := assert.New(t)
+	tb := &fakeTB{}
+	mock := NewT(tb, FailFast())
+
+	a.Error(mock.Ping())
+	a.True(tb.fatal)
+	a.Len(tb.errors, 1)
+}
+
+func TestNewT_ExpectedErrorIsNotReported(t *testing.T) {
+	a := assert.New(t)
+	tb := &fakeTB{}
+	mock := NewT(tb, FailFast())
+	mock.ExpectGet().WithKey("foo").WillReturnError(memcache.ErrCacheMiss)
+
+	_, err := mock.Get("foo")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	tb.cleanup()
+	a.Empty(tb.errors)
+	a.False(tb.fatal)
+}
+
+func TestExpectationsWereMet_ReportsEveryUnmetExpectation(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectGet().WithKey("foo").Times(3)
+	mock.ExpectDelete().WithKey("bar")
+	mock.ExpectPing().Maybe()
+	mock.ExpectTouch().WithKeyAndSeconds("baz", 10)
+	mock.MatchExpectationsInOrder(false)
+
+	_, err := mock.Get("foo")
+	a.NoError(err)
+	a.NoError(mock.Touch("baz", 10))
+
+	err = mock.ExpectationsWereMet()
+	var unmet *UnmetExpectationsError
+	if a.ErrorAs(err, &unmet) && a.Len(unmet.Unmet, 2) {
+		a.IsType(&ExpectedGet{}, unmet.Unmet[0].Expectation)
+		a.Equal(uint(1), unmet.Unmet[0].Called)
+		a.Equal(uint(3), unmet.Unmet[0].Min)
+		a.Equal(uint(3), unmet.Unmet[0].Max)
+		a.IsType(&ExpectedDelete{}, unmet.Unmet[1].Expectation)
+		a.Equal(uint(0), unmet.Unmet[1].Called)
+		a.Equal(uint(1), unmet.Unmet[1].Min)
+	}
+	msg := err.Error()
+	a.Contains(msg, "there are 2 remaining expectations which were not matched:")
+	a.Contains(msg, "\n1. ExpectedGet => expecting call to Get():")
+	a.Contains(msg, "\t- called 1 time, expected 3 times")
+	a.Contains(msg, "\n2. ExpectedDelete => expecting call to Delete():")
+	a.Contains(msg, "\t- called 0 times, expected 1 time")
+}
+
+func TestExpectationsWereMet_ReportsOverCalledExpectations(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectDelete().WithKey("foo").Never()
+	mock.ExpectPing()
+	mock.ExpectTouch().WithKeyAndSeconds("bar", 10).Never()
+
+	a.Error(mock.Delete("foo"))
+	a.Error(mock.Touch("bar", 10))
+	a.Error(mock.Touch("bar", 10))
+
+	err := mock.ExpectationsWereMet()
+	a.EqualError(err, "there is a remaining expectation which was not matched:\n"+
+		"1. ExpectedPing => expecting call to Ping()\n"+
+		"\t- called 0 times, expected 1 time\n"+
+		"there are 2 expectations which were called too many times:\n"+
+		"1. ExpectedDelete => expecting call to Delete():\n"+
+		"\t- is with key: foo\n"+
+		"\t- execution calls awaited: never\n"+
+		"\t- called 1 time, but must never be called\n"+
+		"2. ExpectedTouch => expecting call to Touch():\n"+
+		"\t- is with key: bar\n"+
+		"\t- and with seconds: 10\n"+
+		"\t- execution calls awaited: never\n"+
+		"\t- called 2 times, but must never be called")
+
+	over := UnmetExpectation{Expectation: &ExpectedPing{}, Called: 3, Min: 1, Max: 2}
+	a.Contains(over.String(), "\t- called 3 times, but at most 2 times expected")
+}
+
+func TestExpectationsWereMet_WrappedError(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectPing()
+
+	err := fmt.Errorf("checking mock: %w", mock.ExpectationsWereMet())
+	var unmet *UnmetExpectationsError
+	a.ErrorAs(err, &unmet)
+	a.Contains(err.Error(), "there is a remaining expectation which was not matched:\n1. ExpectedPing")
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// FaultKind tells how the server misbehaves.
+type FaultKind int
+
+const (
+	// Reset closes the connection instead of responding.
+	// The command is still run against the backend, as if the response was lost.
+	Reset FaultKind = iota + 1
+	// ResetMidResponse writes the first half of the response, then closes the connection.
+	ResetMidResponse
+	// Truncate writes the get response up to the middle of the first value, then closes the connection.
+	// For other commands it behaves like ResetMidResponse.
+	Truncate
+	// Slow waits for Fault.Delay before running the command, so the client read times out
+	// when the delay is longer than memcache.Client.Timeout.
+	Slow
+	// ServerError responds with a SERVER_ERROR line holding Fault.Message, without running the command.
+	ServerError
+	// ClientError responds with a CLIENT_ERROR line holding Fault.Message, without running the command.
+	ClientError
+	// Refuse refuses new connections. Connections accepted by the listener are closed right away,
+	// and Server.DialContext fails with a connection refused error.
+	Refuse
+	// SlowConnect makes Server.DialContext wait for Fault.Delay before connecting, so the client
+	// gets a *memcache.ConnectTimeoutError when the delay is longer than memcache.Client.Timeout.
+	SlowConnect
+)
+
+// Fault describes a misbehaviour of the server, injected with Server.InjectFault.
+type Fault struct {
+	Kind FaultKind
+	// Command is the protocol command the fault applies to, e.g. "get", "set" or "incr".
+	// Empty applies to every command. Ignored by Refuse and SlowConnect.
+	Command string
+	// Key is the key the fault applies to. Empty applies to every key. Ignored by Refuse and SlowConnect.
+	Key string
+	// Delay is the delay of Slow and SlowConnect faults.
+	Delay time.Duration
+	// Message is the message of ServerError and ClientError faults.
+	Message string
+	// Times is how many times the fault applies. Zero applies it until ClearFaults is called.
+	Times int
+}
+
+// connectionLevel tells whether the fault applies to connections rather than to commands.
+func (f *Fault) connectionLevel() bool {
+	return f.Kind == Refuse || f.Kind == SlowConnect
+}
+
+// matches tells whether the fault applies to the command with the given fields.
+func (f *Fault) matches(fields []string) bool {
+	if f.connectionLevel() {
+		return false
+	}
+	if f.Command != "" && f.Command != commandName(fields[0]) {
+		return false
+	}
+	if f.Key == "" {
+		return true
+	}
+	keys := fields[1:]
+	if commandName(fields[0]) != "get" && len(keys) > 1 {
+		keys = keys[:1]
+	}
+	for _, key := range keys {
+		if key == f.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// commandName returns the name used to match faults, gets being the same as get.
+func commandName(verb string) string {
+	if verb == "gets" {
+		return "get"
+	}
+	return verb
+}
+
+// InjectFault makes the server misbehave as described by f.
+// Faults are matched in the order they were injected.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fault := f
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// DialContext connects to the server, honouring the Refuse and SlowConnect faults.
+// It is meant to be set as memcache.Client.DialContext.
+func (s *Server) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if f := s.takeFault(func(f *Fault) bool { return f.Kind == Refuse }); f != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	}
+	if f := s.takeFault(func(f *Fault) bool { return f.Kind == SlowConnect }); f != nil {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, address)
+}
+
+// commandFault returns the first fault applying to the command, if any.
+func (s *Server) commandFault(fields []string) *Fault {
+	return s.takeFault(func(f *Fault) bool { return f.matches(fields) })
+}
+
+// refusing tells whether accepted connections should be closed right away.
+func (s *Server) refusing() bool {
+	return s.takeFault(func(f *Fault) bool { return f.Kind == Refuse }) != nil
+}
+
+// takeFault returns the first fault for which match returns true,
+// removing it once applied Times times.
+func (s *Server) takeFault(match func(*Fault) bool) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if !match(f) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// sleep waits for d, or until the server is closed, in which case it returns false.
+func (s *Server) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// truncate returns the response cut in the middle of the first value of a get response,
+// or in the middle of the response when it holds no value.
+func truncate(response []byte) []byte {
+	header := bytes.Index(response, []byte("\r\n"))
+	fields := strings.Fields(string(response[:header+1]))
+	if bytes.HasPrefix(response, []byte("VALUE ")) && len(fields) >= 4 {
+		if size, err := strconv.Atoi(fields[3]); err == nil {
+			return response[:header+2+size/2]
+		}
+	}
+	return response[:len(response)/2]
+}
+
+// faultError is returned by the errorBackend, and written as is by the server
+type faultError struct {
+	line string
+}
+
+func (e *faultError) Error() string {
+	return e.line
+}
+
+// errorBackend fails every command with the same response line
+type errorBackend struct {
+	err *faultError
+}
+
+func newErrorBackend(f *Fault) *errorBackend {
+	prefix, message := "SERVER_ERROR ", f.Message
+	if f.Kind == ClientError {
+		prefix = "CLIENT_ERROR "
+	}
+	if message == "" {
+		message = "injected fault"
+	}
+	return &errorBackend{err: &faultError{line: prefix + message}}
+}
+
+func (b *errorBackend) Add(*memcache.Item) error                             { return b.err }
+func (b *errorBackend) Append(*memcache.Item) error                          { return b.err }
+func (b *errorBackend) CompareAndSwap(*memcache.Item) error                  { return b.err }
+func (b *errorBackend) Decrement(string, uint64) (uint64, error)             { return 0, b.err }
+func (b *errorBackend) Delete(string) error                                  { return b.err }
+func (b *errorBackend) FlushAll() error                                      { return b.err }
+func (b *errorBackend) Get(string) (*memcache.Item, error)                   { return nil, b.err }
+func (b *errorBackend) GetMulti([]string) (map[string]*memcache.Item, error) { return nil, b.err }
+func (b *errorBackend) Increment(string, uint64) (uint64, error)             { return 0, b.err }
+func (b *errorBackend) Ping() error                                          { return b.err }
+func (b *errorBackend) Prepend(*memcache.Item) error                         { return b.err }
+func (b *errorBackend) Replace(*memcache.Item) error                         { return b.err }
+func (b *errorBackend) Set(*memcache.Item) error                             { return b.err }
+func (b *errorBackend) Touch(string, int32) error                            { return b.err }
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/andreluciani/gomemcachemock/memcachemock"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFault_Reset(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	srv.InjectFault(Fault{Kind: Reset, Command: "set", Times: 1})
+	a.Error(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("some value")}))
+	item, err := mc.Get("some-key")
+	a.NoError(err, "the command runs even though the response is lost")
+	a.Equal([]byte("some value"), item.Value)
+}
+
+func TestFault_ResetMidResponse(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	a.NoError(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("some value")}))
+	srv.InjectFault(Fault{Kind: ResetMidResponse, Command: "get", Key: "some-key"})
+	_, err := mc.Get("some-key")
+	a.Error(err)
+	a.NotErrorIs(err, memcache.ErrCacheMiss)
+	srv.ClearFaults()
+	_, err = mc.Get("some-key")
+	a.NoError(err)
+}
+
+func TestFault_Truncate(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	a.NoError(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("some long value")}))
+	a.NoError(mc.Set(&memcache.Item{Key: "another-key", Value: []byte("another value")}))
+	srv.InjectFault(Fault{Kind: Truncate, Key: "some-key"})
+	_, err := mc.Get("some-key")
+	a.Error(err)
+	_, err = mc.GetMulti([]string{"another-key", "some-key"})
+	a.Error(err)
+	_, err = mc.Get("another-key")
+	a.NoError(err)
+	a.Error(mc.Delete("some-key"))
+}
+
+func TestFault_Slow(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	mc.Timeout = 50 * time.Millisecond
+	srv.InjectFault(Fault{Kind: Slow, Command: "get", Delay: 200 * time.Millisecond, Times: 1})
+	_, err := mc.Get("some-key")
+	var netErr net.Error
+	a.True(errors.As(err, &netErr))
+	a.True(netErr.Timeout())
+	_, err = mc.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestFault_ServerAndClientErrors(t *testing.T) {
+	mock := memcachemock.New()
+	srv, mc := newServer(t, mock)
+	a := assert.New(t)
+	srv.InjectFault(Fault{Kind: ServerError, Command: "set", Message: "out of memory storing object", Times: 1})
+	srv.InjectFault(Fault{Kind: ClientError, Command: "incr", Message: "bad command line format", Times: 1})
+	srv.InjectFault(Fault{Kind: ServerError, Command: "get", Times: 1})
+	err := mc.Set(&memcache.Item{Key: "some-key"})
+	a.ErrorContains(err, "SERVER_ERROR out of memory storing object")
+	_, err = mc.Increment("counter", 1)
+	a.EqualError(err, "memcache: client error: bad command line format")
+	_, err = mc.Get("some-key")
+	a.ErrorContains(err, "SERVER_ERROR injected fault")
+	a.NoError(mock.ExpectationsWereMet(), "the backend is not called")
+}
+
+func TestFault_Refuse(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	srv.InjectFault(Fault{Kind: Refuse, Times: 1})
+	a.Error(mc.Ping())
+	a.NoError(mc.Ping())
+
+	a.NoError(mc.Close())
+	mc.DialContext = srv.DialContext
+	srv.InjectFault(Fault{Kind: Refuse, Times: 1})
+	err := mc.Ping()
+	a.ErrorIs(err, syscall.ECONNREFUSED)
+	a.NoError(mc.Ping())
+}
+
+func TestFault_SlowConnect(t *testing.T) {
+	srv, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	mc.Timeout = 50 * time.Millisecond
+	mc.DialContext = srv.DialContext
+	srv.InjectFault(Fault{Kind: SlowConnect, Delay: time.Second, Times: 1})
+	err := mc.Ping()
+	var connectTimeout *memcache.ConnectTimeoutError
+	a.True(errors.As(err, &connectTimeout))
+	a.NoError(mc.Ping())
+}
+
+func TestFault_Matches(t *testing.T) {
+	a := assert.New(t)
+	a.True((&Fault{Kind: Reset}).matches([]string{"version"}))
+	a.True((&Fault{Kind: Reset, Command: "get"}).matches([]string{"gets", "a"}))
+	a.False((&Fault{Kind: Reset, Command: "set"}).matches([]string{"gets", "a"}))
+	a.True((&Fault{Kind: Reset, Key: "b"}).matches([]string{"gets", "a", "b"}))
+	a.False((&Fault{Kind: Reset, Key: "0"}).matches([]string{"set", "a", "0", "0", "1"}))
+	a.False((&Fault{Kind: Refuse}).matches([]string{"version"}))
+}
+
+func TestFault_CloseInterruptsSlow(t *testing.T) {
+	srv, err := New(memcachemock.NewFake())
+	require.NoError(t, err)
+	mc := memcache.New(srv.Addr())
+	mc.Timeout = time.Minute
+	srv.InjectFault(Fault{Kind: Slow, Delay: time.Minute})
+	errs := make(chan error)
+	go func() { errs <- mc.Ping() }()
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, srv.Close())
+	assert.Error(t, <-errs)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
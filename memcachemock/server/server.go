@@ -0,0 +1,374 @@
+/*
+The package server runs a memcached server speaking the ASCII protocol on a local TCP port,
+so code depending on a concrete *memcache.Client can be tested without a real memcached.
+
+The commands received are handled by a Backend, either the mock returned by memcachemock.New
+to assert on expectations, or the in-memory store returned by memcachemock.NewFake.
+*/
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Version is the version reported to the version command.
+const Version = "memcachemock"
+
+// clientErrorPrefix is the prefix of the errors built by *memcache.Client from CLIENT_ERROR lines
+const clientErrorPrefix = "memcache: client error: "
+
+// Backend handles the commands received by the Server.
+// Both the mock returned by memcachemock.New and the fake returned by memcachemock.NewFake implement it.
+type Backend interface {
+	Add(item *memcache.Item) error
+	Append(item *memcache.Item) error
+	CompareAndSwap(item *memcache.Item) error
+	Decrement(key string, delta uint64) (newValue uint64, err error)
+	Delete(key string) error
+	FlushAll() error
+	Get(key string) (item *memcache.Item, err error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Increment(key string, delta uint64) (newValue uint64, err error)
+	Ping() error
+	Prepend(item *memcache.Item) error
+	Replace(item *memcache.Item) error
+	Set(item *memcache.Item) error
+	Touch(key string, seconds int32) (err error)
+}
+
+// Server is a memcached server listening on 127.0.0.1.
+//
+// As the protocol does not tell the client methods apart, a get command with a single key
+// is handled by Backend.Get and with several keys by Backend.GetMulti, the version command
+// by Backend.Ping, and flush_all by Backend.FlushAll, whether the client called FlushAll or DeleteAll.
+type Server struct {
+	backend  Backend
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	done chan struct{} // closed when the server is closed
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	faults []*Fault
+	closed bool
+}
+
+// New starts a Server on a random port of 127.0.0.1, handling commands with backend.
+// The server must be closed with Close.
+func New(backend Backend) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		backend:  backend,
+		listener: listener,
+		done:     make(chan struct{}),
+		conns:    map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address of the server, to be given to memcache.New.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops listening, closes every open connection and waits for them to be released.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	err := s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		if s.refusing() {
+			conn.Close()
+			continue
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if _, err := conn.Write([]byte("ERROR\r\n")); err != nil {
+				return
+			}
+			continue
+		}
+		if fields[0] == "quit" {
+			return
+		}
+
+		var backend Backend = s.backend
+		fault := s.commandFault(fields)
+		if fault != nil {
+			switch fault.Kind {
+			case Slow:
+				if !s.sleep(fault.Delay) {
+					return
+				}
+			case ServerError, ClientError:
+				backend = newErrorBackend(fault)
+			}
+		}
+
+		// the response is buffered, so faults can alter it
+		var response bytes.Buffer
+		rw := bufio.NewReadWriter(r, bufio.NewWriter(&response))
+		if err := s.dispatch(rw, backend, fields); err != nil {
+			return
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+		if fault != nil {
+			switch fault.Kind {
+			case Reset:
+				return
+			case ResetMidResponse:
+				conn.Write(response.Bytes()[:response.Len()/2])
+				return
+			case Truncate:
+				conn.Write(truncate(response.Bytes()))
+				return
+			}
+		}
+		if _, err := conn.Write(response.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch runs a command and writes its response.
+// An error is returned when the connection cannot be used anymore.
+func (s *Server) dispatch(rw *bufio.ReadWriter, backend Backend, fields []string) error {
+	switch fields[0] {
+	case "get", "gets":
+		return s.get(rw, backend, fields[1:])
+	case "set", "add", "replace", "append", "prepend", "cas":
+		return s.store(rw, backend, fields)
+	case "delete":
+		if len(fields) < 2 {
+			return reply(rw, "ERROR")
+		}
+		return s.respond(rw, fields, backend.Delete(fields[1]), "DELETED")
+	case "incr", "decr":
+		return s.incrDecr(rw, backend, fields)
+	case "touch":
+		if len(fields) < 3 {
+			return reply(rw, "ERROR")
+		}
+		seconds, err := strconv.ParseInt(fields[2], 10, 32)
+		if err != nil {
+			return reply(rw, "CLIENT_ERROR invalid exptime argument")
+		}
+		return s.respond(rw, fields, backend.Touch(fields[1], int32(seconds)), "TOUCHED")
+	case "flush_all":
+		return s.respond(rw, fields, backend.FlushAll(), "OK")
+	case "version":
+		if err := backend.Ping(); err != nil {
+			return reply(rw, errorLine(err))
+		}
+		return reply(rw, "VERSION "+Version)
+	}
+	return reply(rw, "ERROR")
+}
+
+func (s *Server) get(rw *bufio.ReadWriter, backend Backend, keys []string) error {
+	if len(keys) == 0 {
+		return reply(rw, "ERROR")
+	}
+	items := map[string]*memcache.Item{}
+	var err error
+	if len(keys) == 1 {
+		var item *memcache.Item
+		item, err = backend.Get(keys[0])
+		if item != nil {
+			items[keys[0]] = item
+		}
+	} else {
+		items, err = backend.GetMulti(keys)
+	}
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return reply(rw, errorLine(err))
+	}
+	for _, key := range keys {
+		item, ok := items[key]
+		if !ok || item == nil {
+			continue
+		}
+		fmt.Fprintf(rw, "VALUE %s %d %d %d\r\n", key, item.Flags, len(item.Value), item.CasID)
+		rw.Write(item.Value)
+		rw.WriteString("\r\n")
+	}
+	return reply(rw, "END")
+}
+
+// store handles the storage commands: <verb> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]
+func (s *Server) store(rw *bufio.ReadWriter, backend Backend, fields []string) error {
+	verb := fields[0]
+	argc := 5
+	if verb == "cas" {
+		argc = 6
+	}
+	if len(fields) < argc {
+		return reply(rw, "ERROR")
+	}
+	flags, errFlags := strconv.ParseUint(fields[2], 10, 32)
+	expiration, errExpiration := strconv.ParseInt(fields[3], 10, 32)
+	size, errSize := strconv.Atoi(fields[4])
+	if errFlags != nil || errExpiration != nil || errSize != nil || size < 0 {
+		return reply(rw, "CLIENT_ERROR bad command line format")
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return err
+	}
+	if string(data[size:]) != "\r\n" {
+		if data[size+1] != '\n' {
+			if _, err := rw.ReadString('\n'); err != nil {
+				return err
+			}
+		}
+		return reply(rw, "CLIENT_ERROR bad data chunk")
+	}
+	item := &memcache.Item{
+		Key:        fields[1],
+		Value:      data[:size],
+		Flags:      uint32(flags),
+		Expiration: int32(expiration),
+	}
+	var err error
+	switch verb {
+	case "set":
+		err = backend.Set(item)
+	case "add":
+		err = backend.Add(item)
+	case "replace":
+		err = backend.Replace(item)
+	case "append":
+		err = backend.Append(item)
+	case "prepend":
+		err = backend.Prepend(item)
+	case "cas":
+		casID, errCasID := strconv.ParseUint(fields[5], 10, 64)
+		if errCasID != nil {
+			return reply(rw, "CLIENT_ERROR bad command line format")
+		}
+		item.CasID = casID
+		err = backend.CompareAndSwap(item)
+	}
+	return s.respond(rw, fields, err, "STORED")
+}
+
+// incrDecr handles the incr and decr commands: <verb> <key> <delta> [noreply]
+func (s *Server) incrDecr(rw *bufio.ReadWriter, backend Backend, fields []string) error {
+	if len(fields) < 3 {
+		return reply(rw, "ERROR")
+	}
+	delta, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return reply(rw, "CLIENT_ERROR invalid numeric delta argument")
+	}
+	var value uint64
+	if fields[0] == "incr" {
+		value, err = backend.Increment(fields[1], delta)
+	} else {
+		value, err = backend.Decrement(fields[1], delta)
+	}
+	return s.respond(rw, fields, err, strconv.FormatUint(value, 10))
+}
+
+// respond writes success, or the line matching err, unless the command ends with noreply.
+func (s *Server) respond(rw *bufio.ReadWriter, fields []string, err error, success string) error {
+	if fields[len(fields)-1] == "noreply" {
+		return nil
+	}
+	if err != nil {
+		return reply(rw, errorLine(err))
+	}
+	return reply(rw, success)
+}
+
+func reply(rw *bufio.ReadWriter, line string) error {
+	_, err := rw.WriteString(line + "\r\n")
+	return err
+}
+
+// errorLine returns the response line read as err by *memcache.Client.
+func errorLine(err error) string {
+	var fault *faultError
+	if errors.As(err, &fault) {
+		return fault.line
+	}
+	switch {
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return "NOT_FOUND"
+	case errors.Is(err, memcache.ErrNotStored):
+		return "NOT_STORED"
+	case errors.Is(err, memcache.ErrCASConflict):
+		return "EXISTS"
+	case errors.Is(err, memcache.ErrMalformedKey):
+		return "CLIENT_ERROR bad command line format"
+	}
+	msg := strings.Join(strings.Fields(err.Error()), " ")
+	if strings.HasPrefix(msg, clientErrorPrefix) {
+		return "CLIENT_ERROR " + strings.TrimPrefix(msg, clientErrorPrefix)
+	}
+	return "SERVER_ERROR " + msg
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/andreluciani/gomemcachemock/memcachemock"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T, backend Backend) (*Server, *memcache.Client) {
+	srv, err := New(backend)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv, memcache.New(srv.Addr())
+}
+
+func TestServer_Fake(t *testing.T) {
+	_, mc := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+
+	a.NoError(mc.Ping())
+	_, err := mc.Get("some-key")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.NoError(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("some value"), Flags: 5, Expiration: 60}))
+	item, err := mc.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("some value"), item.Value)
+	a.Equal(uint32(5), item.Flags)
+
+	a.ErrorIs(mc.Add(&memcache.Item{Key: "some-key"}), memcache.ErrNotStored)
+	a.ErrorIs(mc.Replace(&memcache.Item{Key: "missing-key"}), memcache.ErrNotStored)
+	a.NoError(mc.Append(&memcache.Item{Key: "some-key", Value: []byte("!")}))
+	a.NoError(mc.Prepend(&memcache.Item{Key: "some-key", Value: []byte("> ")}))
+	item, err = mc.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("> some value!"), item.Value)
+
+	a.NoError(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("concurrent")}))
+	item.Value = []byte("stale")
+	a.ErrorIs(mc.CompareAndSwap(item), memcache.ErrCASConflict)
+	item, err = mc.Get("some-key")
+	a.NoError(err)
+	item.Value = []byte("swapped")
+	a.NoError(mc.CompareAndSwap(item))
+
+	a.NoError(mc.Set(&memcache.Item{Key: "counter", Value: []byte("10")}))
+	value, err := mc.Increment("counter", 5)
+	a.NoError(err)
+	a.Equal(uint64(15), value)
+	value, err = mc.Decrement("counter", 20)
+	a.NoError(err)
+	a.Equal(uint64(0), value)
+	_, err = mc.Increment("missing-key", 1)
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	_, err = mc.Increment("some-key", 1)
+	a.EqualError(err, "memcache: client error: cannot increment or decrement non-numeric value")
+
+	items, err := mc.GetMulti([]string{"some-key", "counter", "missing-key"})
+	a.NoError(err)
+	a.Len(items, 2)
+	a.Equal([]byte("swapped"), items["some-key"].Value)
+
+	a.NoError(mc.Touch("some-key", 10))
+	a.ErrorIs(mc.Touch("missing-key", 10), memcache.ErrCacheMiss)
+	a.NoError(mc.Delete("some-key"))
+	a.ErrorIs(mc.Delete("some-key"), memcache.ErrCacheMiss)
+	a.NoError(mc.DeleteAll())
+	a.NoError(mc.FlushAll())
+	_, err = mc.Get("counter")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+}
+
+func TestServer_Mock(t *testing.T) {
+	mock := memcachemock.New()
+	_, mc := newServer(t, mock)
+	a := assert.New(t)
+
+	mock.ExpectSet().
+		WithItemKey("some-key").
+		WithItemValue("some value")
+	mock.ExpectGet().
+		WithKey("some-key").
+		WillReturnItem(&memcache.Item{Key: "some-key", Value: []byte("some value")})
+	mock.ExpectIncrement().
+		WithKeyAndDelta("counter", 1).
+		WillReturnValue(42)
+	mock.ExpectDelete().
+		WithKey("some-key").
+		WillReturnError(memcache.ErrCacheMiss)
+	mock.ExpectFlushAll()
+
+	a.NoError(mc.Set(&memcache.Item{Key: "some-key", Value: []byte("some value")}))
+	item, err := mc.Get("some-key")
+	a.NoError(err)
+	a.Equal([]byte("some value"), item.Value)
+	value, err := mc.Increment("counter", 1)
+	a.NoError(err)
+	a.Equal(uint64(42), value)
+	a.ErrorIs(mc.Delete("some-key"), memcache.ErrCacheMiss)
+	a.NoError(mc.FlushAll())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestServer_MockUnexpectedCall(t *testing.T) {
+	mock := memcachemock.New()
+	_, mc := newServer(t, mock)
+	a := assert.New(t)
+
+	mock.ExpectPing().
+		WillReturnError(memcache.ErrServerError)
+	err := mc.Ping()
+	a.ErrorContains(err, "SERVER_ERROR memcache: server error")
+	err = mc.Set(&memcache.Item{Key: "some-key"})
+	a.ErrorContains(err, "SERVER_ERROR all expectations were already fulfilled, call to method Set() was not expected")
+}
+
+func TestServer_RawProtocol(t *testing.T) {
+	srv, _ := newServer(t, memcachemock.NewFake())
+	a := assert.New(t)
+	conn, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(cmd string) string {
+		_, err := fmt.Fprint(conn, cmd)
+		require.NoError(t, err)
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		return line
+	}
+	a.Equal("ERROR\r\n", send("unknown\r\n"))
+	a.Equal("ERROR\r\n", send("\r\n"))
+	a.Equal("CLIENT_ERROR bad command line format\r\n", send("set k x 0 1\r\n"))
+	a.Equal("CLIENT_ERROR bad data chunk\r\n", send("set k 0 0 1\r\nab\r\n"))
+	a.Equal("STORED\r\n", send("set k 0 0 1\r\na\r\n"))
+	a.Equal("NOT_FOUND\r\n", send("set k 0 0 1 noreply\r\na\r\ndelete missing\r\n"))
+	a.Equal("CLIENT_ERROR invalid numeric delta argument\r\n", send("incr k x\r\n"))
+	a.Equal("CLIENT_ERROR invalid exptime argument\r\n", send("touch k x\r\n"))
+	a.Equal("VERSION memcachemock\r\n", send("version\r\n"))
+	_, err = fmt.Fprint(conn, "quit\r\n")
+	require.NoError(t, err)
+	_, err = r.ReadString('\n')
+	a.Error(err)
+}
+
+func TestServer_Close(t *testing.T) {
+	srv, err := New(memcachemock.NewFake())
+	require.NoError(t, err)
+	mc := memcache.New(srv.Addr())
+	a := assert.New(t)
+	a.NoError(mc.Ping())
+	a.NoError(srv.Close())
+	a.NoError(srv.Close())
+	a.Error(mc.Ping())
+}
+
+func TestServer_RecordAndReplay(t *testing.T) {
+	a := assert.New(t)
+	scenario := func(client memcachemock.Client) []interface{} {
+		_, missErr := client.Get("foo")
+		setErr := client.Set(&memcache.Item{Key: "foo", Value: []byte("bar"), Flags: 1})
+		item, getErr := client.Get("foo")
+		item.Value = []byte("baz")
+		casErr := client.CompareAndSwap(item)
+		items, multiErr := client.GetMulti([]string{"foo", "missing"})
+		return []interface{}{missErr, setErr, getErr, casErr, items, multiErr}
+	}
+	_, mc := newServer(t, memcachemock.NewFake())
+	var cassette bytes.Buffer
+	recorder := memcachemock.NewRecorder(mc, &cassette)
+	recorded := scenario(recorder)
+	require.NoError(t, recorder.Err())
+
+	mock, err := memcachemock.Replay(&cassette)
+	require.NoError(t, err)
+	a.Equal(recorded, scenario(mock))
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,191 @@
+package memcachemock
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// newServerSelector returns a selector routing keys to the given servers, or nil if there are none.
+// Keys are routed the way a memcache.ServerList does, but the addresses are not resolved,
+// so that host names which only resolve where the code is deployed can be used in unit tests.
+func newServerSelector(servers []string) memcache.ServerSelector {
+	if len(servers) == 0 {
+		return nil
+	}
+	ss := make(serverList, len(servers))
+	for i, server := range servers {
+		ss[i] = serverAddr(normalizeAddr(server))
+	}
+	return ss
+}
+
+// serverAddr is the address of a server given to New, as it is named in calls and expectations.
+type serverAddr string
+
+func (a serverAddr) Network() string {
+	if strings.Contains(string(a), "/") {
+		return "unix"
+	}
+	return "tcp"
+}
+
+func (a serverAddr) String() string {
+	return string(a)
+}
+
+// serverList is a memcache.ServerSelector over unresolved addresses.
+type serverList []serverAddr
+
+// PickServer routes key the way memcache.ServerList does, hashing at most its first 256 bytes.
+func (ss serverList) PickServer(key string) (net.Addr, error) {
+	if len(ss) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	if len(ss) == 1 {
+		return ss[0], nil
+	}
+	if len(key) > 256 {
+		key = key[:256]
+	}
+	return ss[crc32.ChecksumIEEE([]byte(key))%uint32(len(ss))], nil
+}
+
+func (ss serverList) Each(f func(net.Addr) error) error {
+	for _, addr := range ss {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailServer makes every call routed to the server addr fail with err,
+// as a *memcache.Client would when the server is down, e.g. with memcache.ErrNoServers.
+// The failing calls do not match any expectation.
+// Ping and FlushAll, which reach every server, fail as well.
+func (c *Mock) FailServer(addr string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures == nil {
+		c.failures = map[string]error{}
+	}
+	c.failures[normalizeAddr(addr)] = err
+}
+
+// RecoverServer stops the failures injected on the server addr with FailServer.
+func (c *Mock) RecoverServer(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.failures, normalizeAddr(addr))
+}
+
+// route records the server key is routed to, and returns it along with the error injected on it, if any.
+// A key the selector cannot route fails with the error of the selector, e.g. memcache.ErrNoServers.
+// Without servers, keys are not routed, and an empty server is returned.
+// In strict mode, an invalid key fails before being routed.
+func (c *Mock) route(call *Call, key string) (string, error) {
+	if err := c.validateKey(key); err != nil {
+		return "", err
+	}
+	if c.selector == nil {
+		return "", nil
+	}
+	addr, err := c.selector.PickServer(key)
+	if err != nil {
+		return "", err
+	}
+	server := addr.String()
+	c.callsMu.Lock()
+	call.Server = server
+	c.callsMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return server, c.failures[server]
+}
+
+// routeItem routes the key of item, if any.
+// In strict mode, an invalid item fails before being routed.
+func (c *Mock) routeItem(call *Call, item *memcache.Item) (string, error) {
+	if item == nil {
+		return "", nil
+	}
+	if err := c.validateItem(call.Method, item); err != nil {
+		return "", err
+	}
+	return c.route(call, item.Key)
+}
+
+// routeKeys returns the error injected on a server any of keys is routed to, or the error of the selector, if any.
+// In strict mode, any invalid key fails the call.
+func (c *Mock) routeKeys(keys []string) error {
+	for _, key := range keys {
+		if err := c.validateKey(key); err != nil {
+			return err
+		}
+	}
+	if c.selector == nil {
+		return nil
+	}
+	for _, key := range keys {
+		addr, err := c.selector.PickServer(key)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		err = c.failures[addr.String()]
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failingServer returns the error injected on any server, if any.
+func (c *Mock) failingServer() error {
+	if c.selector == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var failure error
+	_ = c.selector.Each(func(addr net.Addr) error {
+		if err, ok := c.failures[addr.String()]; ok && err != nil {
+			failure = err
+			return err
+		}
+		return nil
+	})
+	return failure
+}
+
+// normalizeAddr returns addr the way a memcache.ServerList names it when its host is an IP address.
+// Host names are kept as given, without resolving them.
+func normalizeAddr(addr string) string {
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return net.JoinHostPort(ip.String(), port)
+	}
+	return addr
+}
+
+// serverMatches checks the server a call was routed to against the one set with OnServer, if any.
+func (e *commonExpectation) serverMatches(server string) error {
+	if e.server == "" || e.server == server {
+		return nil
+	}
+	if server == "" {
+		return fmt.Errorf("expected call on server %s, but the mock has no servers", e.server)
+	}
+	return fmt.Errorf("expected call on server %s, but the key was routed to server %s", e.server, server)
+}
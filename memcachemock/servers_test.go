@@ -0,0 +1,206 @@
+package memcachemock
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	serverA = "10.0.0.1:11211"
+	serverB = "10.0.0.2:11211"
+)
+
+// keysByServer returns a key routed to each of serverA and serverB
+func keysByServer(t *testing.T) (keyA, keyB string) {
+	ss := new(memcache.ServerList)
+	require.NoError(t, ss.SetServers(serverA, serverB))
+	for i := 0; keyA == "" || keyB == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		addr, err := ss.PickServer(key)
+		require.NoError(t, err)
+		if addr.String() == serverA {
+			keyA = key
+		} else {
+			keyB = key
+		}
+	}
+	return keyA, keyB
+}
+
+func TestServers_RecordRouting(t *testing.T) {
+	a := assert.New(t)
+	keyA, keyB := keysByServer(t)
+	mock := New(serverA, serverB)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectGet().WithKey(keyA)
+	mock.ExpectSet().WithItemKey(keyB)
+
+	_, err := mock.Get(keyA)
+	a.NoError(err)
+	a.NoError(mock.Set(&memcache.Item{Key: keyB}))
+
+	calls := mock.Calls()
+	if a.Len(calls, 2) {
+		a.Equal(serverA, calls[0].Server)
+		a.Equal(serverB, calls[1].Server)
+	}
+}
+
+func TestServers_WithoutServers(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.ExpectGet().WithKey("foo")
+	_, err := mock.Get("foo")
+	a.NoError(err)
+	a.Empty(mock.Calls()[0].Server)
+
+	mock.ExpectGet().WithKey("foo").OnServer(serverA)
+	_, err = mock.Get("foo")
+	if a.Error(err) {
+		a.Contains(err.Error(), "expected call on server 10.0.0.1:11211, but the mock has no servers")
+	}
+}
+
+func TestOnServer(t *testing.T) {
+	a := assert.New(t)
+	keyA, keyB := keysByServer(t)
+	mock := New(serverA, serverB)
+	mock.ExpectDelete().WithKey(AnyKey()).OnServer(serverB)
+	a.Contains(mock.expectations[0].String(), "is on server: 10.0.0.2:11211")
+
+	err := mock.Delete(keyA)
+	if a.Error(err) {
+		a.Contains(err.Error(), "expected call on server 10.0.0.2:11211, but the key was routed to server 10.0.0.1:11211")
+	}
+	a.NoError(mock.Delete(keyB))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestFailServer(t *testing.T) {
+	a := assert.New(t)
+	keyA, keyB := keysByServer(t)
+	mock := New(serverA, serverB)
+	mock.FailServer(serverB, memcache.ErrNoServers)
+	mock.ExpectGet().WithKey(keyA)
+
+	_, err := mock.Get(keyB)
+	a.ErrorIs(err, memcache.ErrNoServers)
+	_, err = mock.Increment(keyB, 1)
+	a.ErrorIs(err, memcache.ErrNoServers)
+	_, err = mock.GetMulti([]string{keyA, keyB})
+	a.ErrorIs(err, memcache.ErrNoServers)
+	a.ErrorIs(mock.Ping(), memcache.ErrNoServers)
+	a.ErrorIs(mock.FlushAll(), memcache.ErrNoServers)
+
+	_, err = mock.Get(keyA)
+	a.NoError(err, "the other server still works")
+	a.NoError(mock.ExpectationsWereMet(), "failing calls do not match expectations")
+
+	mock.RecoverServer(serverB)
+	mock.ExpectGet().WithKey(keyB)
+	_, err = mock.Get(keyB)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestFailServer_DeleteAll(t *testing.T) {
+	a := assert.New(t)
+	ss := new(memcache.ServerList)
+	require.NoError(t, ss.SetServers(serverA, serverB))
+	addr, err := ss.PickServer("")
+	require.NoError(t, err)
+	reached, other := addr.String(), serverA
+	if reached == serverA {
+		other = serverB
+	}
+	mock := New(serverA, serverB)
+	mock.ExpectDeleteAll()
+
+	mock.FailServer(other, memcache.ErrNoServers)
+	a.NoError(mock.DeleteAll(), "only the server of the empty key is reached")
+	a.Equal(reached, mock.Calls()[0].Server)
+	mock.FailServer(reached, memcache.ErrNoServers)
+	a.ErrorIs(mock.DeleteAll(), memcache.ErrNoServers)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNewFromSelector_Routing(t *testing.T) {
+	a := assert.New(t)
+	keyA, _ := keysByServer(t)
+	var ss memcache.ServerSelector = func() *memcache.ServerList {
+		sl := new(memcache.ServerList)
+		_ = sl.SetServers(serverA, serverB)
+		return sl
+	}()
+	mock := NewFromSelector(&ss)
+	mock.ExpectTouch().WithKeyAndSeconds(keyA, 10).OnServer(serverA)
+	a.NoError(mock.Touch(keyA, 10))
+	a.Equal(serverA, mock.Calls()[0].Server)
+}
+
+func TestNewFromSelector_NoServers(t *testing.T) {
+	a := assert.New(t)
+	var ss memcache.ServerSelector = new(memcache.ServerList)
+	mock := NewFromSelector(&ss)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectGet().WithKey("foo")
+	mock.ExpectSet().WithItemKey("foo")
+	mock.ExpectGetMulti().WithKeys([]string{"foo"})
+	client := memcache.NewFromSelector(ss)
+
+	_, err := mock.Get("foo")
+	a.ErrorIs(err, memcache.ErrNoServers)
+	_, err = client.Get("foo")
+	a.ErrorIs(err, memcache.ErrNoServers)
+	a.ErrorIs(mock.Set(&memcache.Item{Key: "foo"}), memcache.ErrNoServers)
+	a.ErrorIs(client.Set(&memcache.Item{Key: "foo"}), memcache.ErrNoServers)
+	_, err = mock.GetMulti([]string{"foo"})
+	a.ErrorIs(err, memcache.ErrNoServers)
+	_, err = client.GetMulti([]string{"foo"})
+	a.ErrorIs(err, memcache.ErrNoServers)
+	a.ErrorIs(mock.Calls()[0].Err, memcache.ErrNoServers)
+	a.Len(mock.Pending(), 3, "the calls should not match expectations")
+}
+
+func TestNew_UnresolvedServers(t *testing.T) {
+	a := assert.New(t)
+	mock := New("memcached:11211", "no-such-host.invalid:11211")
+	mock.ExpectGet().WithKey("foo")
+	mock.ExpectSet().WithItemKey(AnyKey()).OnServer("no-such-host.invalid:11211")
+	mock.FailServer("memcached:11211", memcache.ErrServerError)
+	mock.RecoverServer("memcached:11211")
+
+	_, err := mock.Get("foo")
+	a.NoError(err)
+	a.Contains([]string{"memcached:11211", "no-such-host.invalid:11211"}, mock.Calls()[0].Server)
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if addr, _ := mock.selector.PickServer(key); addr.String() == "no-such-host.invalid:11211" {
+			a.NoError(mock.Set(&memcache.Item{Key: key}))
+			break
+		}
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNew_RoutesAsServerList(t *testing.T) {
+	a := assert.New(t)
+	servers := []string{serverA, serverB, "[::1]:11211", "/tmp/memcached.sock"}
+	ss := new(memcache.ServerList)
+	require.NoError(t, ss.SetServers(servers...))
+	mock := New(servers...)
+	for i := 0; i < 100; i++ {
+		key := strings.Repeat("k", 3*i) + fmt.Sprint(i) // up to 300 bytes, beyond the 256 hashed
+		want, err := ss.PickServer(key)
+		require.NoError(t, err)
+		got, err := mock.selector.PickServer(key)
+		require.NoError(t, err)
+		a.Equal(want.String(), got.String())
+		a.Equal(want.Network(), got.Network())
+	}
+}
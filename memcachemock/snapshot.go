@@ -0,0 +1,99 @@
+package memcachemock
+
+// ExpectationsSnapshot is the state of a mock captured by Snapshot.
+// It holds copies of the expectations, so that changing an expectation after the snapshot,
+// e.g. with WillReturnError, Times or InOrder, is undone by Restore.
+// A snapshot may be restored several times, on any mock, including from parallel subtests.
+type ExpectationsSnapshot struct {
+	ordered      bool
+	expectations []Expectation
+	calls        []Call
+}
+
+// Reset drops every expectation and recorded call.
+// The options of the mock, whether expectations are matched in order, its servers and server failures are kept.
+func (c *Mock) Reset() {
+	c.mu.Lock()
+	c.expectations = nil
+	c.mu.Unlock()
+	c.callsMu.Lock()
+	c.calls = nil
+	c.callsMu.Unlock()
+}
+
+// Pending returns the expectations which were not called enough times yet, in order.
+// Unlike ExpectationsWereMet, expectations called too many times are not returned.
+func (c *Mock) Pending() []UnmetExpectation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var pending []UnmetExpectation
+	for _, e := range c.expectations {
+		e.Lock()
+		if called, min, max := e.calls(); called < min {
+			pending = append(pending, UnmetExpectation{Expectation: e, Called: called, Min: min, Max: max})
+		}
+		e.Unlock()
+	}
+	return pending
+}
+
+// Snapshot captures copies of the expectations of the mock, how many times they were called,
+// whether they are matched in order, and the recorded calls, so that a common setup can be restored before each subtest.
+func (c *Mock) Snapshot() *ExpectationsSnapshot {
+	c.mu.Lock()
+	s := &ExpectationsSnapshot{ordered: c.ordered, expectations: cloneExpectations(c.expectations)}
+	c.mu.Unlock()
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	for _, call := range c.calls {
+		s.calls = append(s.calls, *call)
+	}
+	return s
+}
+
+// Restore brings the mock back to the state captured by Snapshot:
+// the expectations are replaced by new copies of the captured ones, and the recorded calls by the captured calls.
+func (c *Mock) Restore(s *ExpectationsSnapshot) {
+	expectations := cloneExpectations(s.expectations)
+	c.mu.Lock()
+	c.ordered = s.ordered
+	c.expectations = expectations
+	c.mu.Unlock()
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	c.calls = nil
+	for i := range s.calls {
+		call := s.calls[i]
+		c.calls = append(c.calls, &call)
+	}
+}
+
+// cloneExpectations copies expectations, linking the copies to the copies of their prerequisites and groups.
+// Prerequisites and groups are read without locking the expectations: InOrder and InAnyOrder only change them
+// under the lock of the mock, held by Snapshot, and never change the expectations of a snapshot.
+func cloneExpectations(expectations []Expectation) []Expectation {
+	clones := make(map[Expectation]Expectation, len(expectations))
+	cloned := make([]Expectation, len(expectations))
+	for i, e := range expectations {
+		e.Lock()
+		cloned[i] = e.clone()
+		e.Unlock()
+		clones[e] = cloned[i]
+	}
+	groups := map[*anyOrderGroup]*anyOrderGroup{}
+	for i, e := range expectations {
+		for _, before := range e.prerequisites() {
+			if clone, ok := clones[before]; ok {
+				before = clone
+			}
+			cloned[i].addPrerequisite(before)
+		}
+		if g := e.anyOrderGroup(); g != nil {
+			if groups[g] == nil {
+				groups[g] = &anyOrderGroup{}
+			}
+			cloned[i].setAnyOrderGroup(groups[g])
+		}
+	}
+	return cloned
+}
@@ -0,0 +1,208 @@
+package memcachemock
+
+import (
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReset(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectGet().WithKey("foo").WillReturnError(memcache.ErrCacheMiss)
+	mock.ExpectDelete().WithKey("foo")
+	_, err := mock.Get("foo")
+	a.ErrorIs(err, memcache.ErrCacheMiss)
+	a.Error(mock.ExpectationsWereMet())
+
+	mock.Reset()
+	a.NoError(mock.ExpectationsWereMet())
+	a.Empty(mock.Calls())
+	a.Empty(mock.Pending())
+	mock.ExpectPing()
+	mock.ExpectClose()
+	a.NoError(mock.Close(), "the ordering option should be kept")
+	a.NoError(mock.Ping())
+}
+
+func TestPending(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	get := mock.ExpectGet().WithKey("foo")
+	get.Times(2)
+	mock.ExpectSet().WithItem(&memcache.Item{Key: "foo"}).Maybe()
+	never := mock.ExpectDelete().WithKey("foo")
+	never.Never()
+	touch := mock.ExpectTouch().WithKeyAndSeconds("foo", 10)
+
+	pending := mock.Pending()
+	if a.Len(pending, 2) {
+		a.Equal(UnmetExpectation{Expectation: get, Called: 0, Min: 2, Max: 2}, pending[0])
+		a.Equal(UnmetExpectation{Expectation: touch, Called: 0, Min: 1, Max: 1}, pending[1])
+	}
+
+	_, _ = mock.Get("foo")
+	_, _ = mock.Get("foo")
+	a.NoError(mock.Touch("foo", 10))
+	a.Error(mock.Delete("foo"))
+	a.Empty(mock.Pending())
+	a.Error(mock.ExpectationsWereMet(), "a call to a Never expectation is not pending, but unmet")
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.ExpectPing()
+	a.NoError(mock.Ping())
+	mock.ExpectGet().WithKey("config").WillReturnItem(&memcache.Item{Key: "config", Value: []byte("v1")})
+	baseline := mock.Snapshot()
+
+	cases := []struct {
+		name    string
+		setup   func()
+		run     func() error
+		pending int
+		calls   int
+	}{
+		{
+			name:  "get only",
+			setup: func() {},
+			run: func() error {
+				_, err := mock.Get("config")
+				return err
+			},
+			pending: 1,
+			calls:   2,
+		},
+		{
+			name:  "get then delete",
+			setup: func() { mock.ExpectDelete().WithKey("config") },
+			run: func() error {
+				if _, err := mock.Get("config"); err != nil {
+					return err
+				}
+				return mock.Delete("config")
+			},
+			pending: 2,
+			calls:   3,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			mock.Restore(baseline)
+			tc.setup()
+			a.Len(mock.Pending(), tc.pending, "the ping call should still count")
+			a.NoError(tc.run())
+			a.NoError(mock.ExpectationsWereMet())
+			a.Len(mock.Calls(), tc.calls)
+		})
+	}
+
+	mock.Restore(baseline)
+	a.Len(mock.Calls(), 1)
+	a.Len(mock.Pending(), 1)
+	a.Error(mock.Delete("config"), "expectations added after the snapshot should be dropped")
+}
+
+func TestSnapshot_KeepsOrdering(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.MatchExpectationsInOrder(false)
+	snapshot := mock.Snapshot()
+	mock.MatchExpectationsInOrder(true)
+	mock.Restore(snapshot)
+	mock.ExpectPing()
+	mock.ExpectClose()
+	a.NoError(mock.Close())
+	a.NoError(mock.Ping())
+}
+
+func TestRestore_UndoesChangesToExpectations(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	get := mock.ExpectGet().WithKey("foo").WillReturnItem(&memcache.Item{Key: "foo", Value: []byte("bar")})
+	set := mock.ExpectSet().WithItemKey("foo")
+	snapshot := mock.Snapshot()
+
+	get.WillReturnError(memcache.ErrCacheMiss)
+	get.Times(2)
+	set.WithItemValue("baz")
+	mock.InOrder(set, get)
+
+	mock.Restore(snapshot)
+	item, err := mock.Get("foo")
+	a.NoError(err)
+	a.Equal([]byte("bar"), item.Value)
+	a.NoError(mock.Set(&memcache.Item{Key: "foo", Value: []byte("other")}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRestore_KeepsLinksBetweenCopies(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	mock.MatchExpectationsInOrder(false)
+	get := mock.ExpectGet().WithKey("foo")
+	set := mock.ExpectSet().WithItemKey("foo")
+	mock.InOrder(get, set)
+	snapshot := mock.Snapshot()
+
+	for i := 0; i < 2; i++ {
+		mock.Restore(snapshot)
+		a.Error(mock.Set(&memcache.Item{Key: "foo"}), "the copy of set should be after the copy of get")
+		_, err := mock.Get("foo")
+		a.NoError(err)
+		a.NoError(mock.Set(&memcache.Item{Key: "foo"}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestRestore_AnyOrderGroups(t *testing.T) {
+	a := assert.New(t)
+	mock := New()
+	getA := mock.ExpectGet().WithKey("a")
+	getB := mock.ExpectGet().WithKey("b")
+	mock.InAnyOrder(getA, getB)
+	getC := mock.ExpectGet().WithKey("c")
+	getD := mock.ExpectGet().WithKey("d")
+	mock.InAnyOrder(getC, getD)
+	snapshot := mock.Snapshot()
+
+	mock.Restore(snapshot)
+	_, err := mock.Get("c")
+	a.Error(err, "the groups should stay distinct")
+	for _, key := range []string{"b", "a", "d", "c"} {
+		_, err := mock.Get(key)
+		a.NoError(err)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRestore_ParallelSubtests(t *testing.T) {
+	baseline := New()
+	baseline.ExpectPing()
+	baseline.ExpectGet().WithKey("config").WillReturnItem(&memcache.Item{Key: "config", Value: []byte("v1")})
+	snapshot := baseline.Snapshot()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		key := key
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			a := assert.New(t)
+			mock := New()
+			mock.Restore(snapshot)
+			mock.ExpectDelete().WithKey(key)
+			a.NoError(mock.Ping())
+			_, err := mock.Get("config")
+			a.NoError(err)
+			a.NoError(mock.Delete(key))
+			a.NoError(mock.ExpectationsWereMet())
+			a.Len(mock.Calls(), 3)
+		})
+	}
+	t.Cleanup(func() {
+		assert.Len(t, baseline.Pending(), 2, "the baseline should not be called by the subtests")
+	})
+}
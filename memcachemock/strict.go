@@ -0,0 +1,77 @@
+package memcachemock
+
+import (
+	"fmt"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// maxKeyLength is the longest key accepted by a *memcache.Client
+const maxKeyLength = 250
+
+// maxItemSize is the largest value stored by a memcached server with its default settings, 1MB
+const maxItemSize = 1024 * 1024
+
+// storageVerbs are the memcached commands sent by the methods storing an item
+var storageVerbs = map[string]string{
+	"Add":            "add",
+	"Append":         "append",
+	"CompareAndSwap": "cas",
+	"Prepend":        "prepend",
+	"Replace":        "replace",
+	"Set":            "set",
+}
+
+// Strict makes the mock validate keys and values the way a *memcache.Client and a memcached server do:
+// a key longer than 250 bytes, or holding spaces or control characters, fails with memcache.ErrMalformedKey,
+// and a value larger than 1MB fails with the error returned by the client on a SERVER_ERROR response.
+// The invalid calls do not match any expectation.
+func Strict() Option {
+	return func(c *Mock) {
+		c.strict = true
+	}
+}
+
+// With applies options to the mock, e.g. New("localhost:11211").With(Strict()).
+func (c *Mock) With(opts ...Option) *Mock {
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// validateKey returns memcache.ErrMalformedKey in strict mode if key would be rejected by a *memcache.Client.
+func (c *Mock) validateKey(key string) error {
+	if c.strict && !legalKey(key) {
+		return memcache.ErrMalformedKey
+	}
+	return nil
+}
+
+// validateItem validates the key of item in strict mode, and the size of its value,
+// as a memcached server would when called by method.
+func (c *Mock) validateItem(method string, item *memcache.Item) error {
+	if !c.strict || item == nil {
+		return nil
+	}
+	if err := c.validateKey(item.Key); err != nil {
+		return err
+	}
+	if len(item.Value) > maxItemSize {
+		return fmt.Errorf("memcache: unexpected response line from %q: %q", storageVerbs[method], "SERVER_ERROR object too large for cache\r\n")
+	}
+	return nil
+}
+
+// legalKey follows the rules of *memcache.Client keys
+func legalKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package memcachemock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrict_MalformedKeys(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211").With(Strict())
+	keys := []string{"invalid key", "tab\tkey", "new\nline", "del\x7f", strings.Repeat("k", maxKeyLength+1)}
+	item := func(key string) *memcache.Item { return &memcache.Item{Key: key} }
+
+	for _, key := range keys {
+		_, err := mock.Get(key)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		_, err = mock.GetMulti([]string{"valid", key})
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Delete(key), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Touch(key, 1), memcache.ErrMalformedKey, key)
+		_, err = mock.Increment(key, 1)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		_, err = mock.Decrement(key, 1)
+		a.ErrorIs(err, memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Set(item(key)), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Add(item(key)), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Replace(item(key)), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Append(item(key)), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.Prepend(item(key)), memcache.ErrMalformedKey, key)
+		a.ErrorIs(mock.CompareAndSwap(item(key)), memcache.ErrMalformedKey, key)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestStrict_ValidKeys(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211").With(Strict())
+	key := strings.Repeat("k", maxKeyLength)
+	mock.ExpectGet().WithKey(key)
+	mock.ExpectSet().WithItemKey("user:1/é")
+
+	_, err := mock.Get(key)
+	a.NoError(err)
+	a.NoError(mock.Set(&memcache.Item{Key: "user:1/é"}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestStrict_ValueTooLarge(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211").With(Strict())
+	mock.ExpectSet().WithItemKey("big")
+
+	err := mock.Set(&memcache.Item{Key: "big", Value: make([]byte, maxItemSize+1)})
+	if a.Error(err) {
+		a.Equal(`memcache: unexpected response line from "set": "SERVER_ERROR object too large for cache\r\n"`, err.Error())
+	}
+	err = mock.CompareAndSwap(&memcache.Item{Key: "big", Value: make([]byte, maxItemSize+1)})
+	if a.Error(err) {
+		a.Contains(err.Error(), `from "cas"`)
+	}
+	a.NoError(mock.Set(&memcache.Item{Key: "big", Value: make([]byte, maxItemSize)}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNotStrict(t *testing.T) {
+	a := assert.New(t)
+	mock := New("localhost:11211")
+	mock.ExpectGet().WithKey("invalid key")
+	_, err := mock.Get("invalid key")
+	a.NoError(err)
+}